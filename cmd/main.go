@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"time"
 
 	"github.com/murakmii/exp-h2server/h2server"
 )
@@ -22,10 +26,23 @@ func main() {
 		Preface: func() *h2server.SettingsFrameBuilder {
 			return h2server.NewSettingsFrameBuilder()
 		},
-		Multiplexer: h2server.DefaultMultiplexer(logger),
+		Handler: h2server.HandlerFunc(func(w h2server.ResponseWriter, r *h2server.Request) {
+			w.AddHeader("content-type", "text/plain")
+			fmt.Fprintf(w, "%s %s\n", r.Method, r.Path)
+		}),
 	})
 
-	if err := sv.ListenAndServe(); err != nil {
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		<-sig
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		sv.Shutdown(ctx)
+	}()
+
+	if err := sv.ListenAndServe(); err != nil && err != h2server.ErrServerClosed {
 		panic(err)
 	}
 }
@@ -0,0 +1,142 @@
+package h2server
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/murakmii/exp-h2server/h2server/hpack"
+)
+
+type (
+	// requestBody buffers DATA frames of a request until handler reads them.
+	// Size of the buffer is bounded by flow control window because data is credited to peer only after it's read.
+	requestBody struct {
+		mu     sync.Mutex
+		cond   *sync.Cond
+		buf    bytes.Buffer
+		err    error
+		closed bool
+		eof    bool
+
+		// trailer is trailer fields received at the end of the request.
+		trailer hpack.HeaderList
+
+		// consumed is called with size of data read or discarded without lock, to credit it to peer.
+		consumed func(n int, discarded bool)
+	}
+)
+
+var (
+	_ io.ReadCloser = (*requestBody)(nil)
+
+	errBodyClosed = errors.New("h2server: read on closed body")
+)
+
+func newRequestBody(consumed func(n int, discarded bool)) *requestBody {
+	body := &requestBody{consumed: consumed}
+	body.cond = sync.NewCond(&body.mu)
+	return body
+}
+
+// write buffers data received from peer.
+// It returns false if the data is discarded because the body is closed or finished.
+func (body *requestBody) write(data []byte) bool {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed || body.err != nil {
+		return false
+	}
+
+	body.buf.Write(data)
+	body.cond.Broadcast()
+	return true
+}
+
+// finish makes Read return the error after buffered data is read.
+// It's io.EOF if peer ends the stream, or error of reset.
+func (body *requestBody) finish(err error) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.err == nil {
+		body.err = err
+		body.cond.Broadcast()
+	}
+}
+
+// finishTrailer ends the body with trailer fields received by HEADERS frame.
+func (body *requestBody) finishTrailer(trailer hpack.HeaderList) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.err == nil {
+		body.trailer = trailer
+		body.err = io.EOF
+		body.cond.Broadcast()
+	}
+}
+
+// trailers returns trailer fields after Read reaches EOF.
+func (body *requestBody) trailers() hpack.HeaderList {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if !body.eof {
+		return nil
+	}
+	return body.trailer
+}
+
+// Read returns error of RST_STREAM as StreamError if peer resets the stream.
+func (body *requestBody) Read(p []byte) (int, error) {
+	body.mu.Lock()
+
+	for {
+		if body.closed {
+			body.mu.Unlock()
+			return 0, errBodyClosed
+		}
+
+		if body.buf.Len() > 0 {
+			n, _ := body.buf.Read(p)
+			body.mu.Unlock()
+
+			body.consumed(n, false)
+			return n, nil
+		}
+
+		if body.err != nil {
+			err := body.err
+			body.eof = err == io.EOF
+			body.mu.Unlock()
+			return 0, err
+		}
+
+		body.cond.Wait()
+	}
+}
+
+// Close discards buffered data and following DATA frames.
+// If peer is still sending the request, the stream is reset after the response.
+func (body *requestBody) Close() error {
+	body.mu.Lock()
+
+	if body.closed {
+		body.mu.Unlock()
+		return nil
+	}
+
+	n := body.buf.Len()
+	body.closed = true
+	body.buf.Reset()
+	body.cond.Broadcast()
+	body.mu.Unlock()
+
+	if n > 0 {
+		body.consumed(n, true)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package h2server
+
+import (
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+)
+
+// testCredit records data credited by requestBody.
+type testCredit struct {
+	mu        sync.Mutex
+	read      int
+	discarded int
+}
+
+func (c *testCredit) consumed(n int, discarded bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if discarded {
+		c.discarded += n
+	} else {
+		c.read += n
+	}
+}
+
+func TestRequestBody_Read(t *testing.T) {
+	credit := &testCredit{}
+	body := newRequestBody(credit.consumed)
+
+	go func() {
+		body.write([]byte("hello, "))
+		body.write([]byte("world"))
+		body.finish(io.EOF)
+	}()
+
+	got, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatalf("Read() got error = %v", err)
+	}
+
+	if string(got) != "hello, world" {
+		t.Errorf("Read() = %s, want = hello, world", got)
+	}
+
+	if credit.read != len(got) || credit.discarded != 0 {
+		t.Errorf("credited got = %+v, want = %d octets read", credit, len(got))
+	}
+
+	if body.write([]byte("after EOF")) {
+		t.Error("write() got = true after EOF")
+	}
+}
+
+func TestRequestBody_Close(t *testing.T) {
+	credit := &testCredit{}
+	body := newRequestBody(credit.consumed)
+	body.write([]byte("data"))
+
+	if err := body.Close(); err != nil {
+		t.Fatalf("Close() got error = %v", err)
+	}
+
+	if body.write([]byte("discarded")) {
+		t.Error("write() got = true after Close()")
+	}
+
+	if _, err := body.Read(make([]byte, 10)); err != errBodyClosed {
+		t.Errorf("Read() got error = %v, want = %v", err, errBodyClosed)
+	}
+
+	if credit.read != 0 || credit.discarded != 4 {
+		t.Errorf("credited got = %+v, want = 4 octets discarded", credit)
+	}
+}
@@ -1,53 +1,308 @@
 package h2server
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"net"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type (
 	Conn interface {
 		RemoteAddr() net.Addr
-		Write(f Frame)
+
+		// ConnectionState returns state of TLS, or nil if the connection isn't TLS.
+		ConnectionState() *tls.ConnectionState
+
+		// PaddingPolicy returns policy for padding of outgoing DATA, HEADERS and PUSH_PROMISE frames.
+		PaddingPolicy() PaddingPolicy
+
+		// LocalSettings returns our settings acknowledged by peer.
+		LocalSettings() Settings
+
+		// PeerSettings returns settings received from peer.
+		PeerSettings() Settings
+
+		// RTT returns smoothed round trip time measured by PING, or zero if it's not measured yet.
+		RTT() time.Duration
+
+		// WriteStats returns statistics of frames flushed to the connection so far.
+		WriteStats() WriteStats
+
+		// UpdateSettings sends new SETTINGS frame to change our settings at runtime.
+		// New settings take effect when peer acknowledges them.
+		UpdateSettings(ctx context.Context, settings *SettingsFrameBuilder) error
+
+		// Write queues the frames to be written contiguously, such as HEADERS and following CONTINUATION frames.
+		// It blocks while the write queue is full.
+		// It returns error if the context is done or the connection is closed before the frames are queued.
+		Write(ctx context.Context, frames ...Frame) error
+
+		// Flush waits until frames queued before are flushed to the connection.
+		Flush(ctx context.Context) error
+
+		// Close stops accepting frames to write. Frames queued already are written.
 		Close()
 	}
 
 	pseudoConn struct {
-		addr   net.Addr
-		w      chan Frame
-		closed int32
+		source   net.Conn
+		framer   *Framer
+		addr     net.Addr
+		tls      *tls.ConnectionState
+		padding  PaddingPolicy
+		settings *connSettings
+		timeout  time.Duration
+		rtt      int64
+
+		// w is bounded write queue. priority is queue for frames written ahead of w.
+		// closed is closed by Close, and done is closed when writer stops.
+		w         chan *outgoing
+		priority  chan Frame
+		closed    chan struct{}
+		closeOnce sync.Once
+		done      chan struct{}
+		doneOnce  sync.Once
+
+		// frameSizes are max frame sizes advertised by peer's SETTINGS whose ACK isn't written yet.
+		sizeMu     sync.Mutex
+		frameSizes []uint32
+	}
+
+	// outgoing is an entry of write queue. It's a request to notify of flush if frames are empty.
+	// settingsACK reports whether the frames are ACK for peer's SETTINGS.
+	outgoing struct {
+		frames      []Frame
+		flushed     chan struct{}
+		settingsACK bool
 	}
 )
 
+const (
+	// writeQueueSize is number of frames that can be queued before Write blocks.
+	writeQueueSize = 64
+
+	// priorityQueueSize is number of frames such as PING ACK that can be queued ahead of other frames.
+	priorityQueueSize = 16
+)
+
 var (
 	_ Conn = (*pseudoConn)(nil)
+
+	ErrConnClosed = errors.New("h2server: connection closed")
 )
 
-func newPseudoConn(source net.Conn) *pseudoConn {
-	return &pseudoConn{
-		addr: source.RemoteAddr(),
-		w:    make(chan Frame),
+func newPseudoConn(source net.Conn, framer *Framer, padding PaddingPolicy, settings *connSettings, settingsTimeout time.Duration) *pseudoConn {
+	pc := &pseudoConn{
+		source:   source,
+		framer:   framer,
+		addr:     source.RemoteAddr(),
+		padding:  padding,
+		settings: settings,
+		timeout:  settingsTimeout,
+		w:        make(chan *outgoing, writeQueueSize),
+		priority: make(chan Frame, priorityQueueSize),
+		closed:   make(chan struct{}),
+		done:     make(chan struct{}),
 	}
+
+	if tlsConn, ok := source.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		pc.tls = &state
+	}
+	return pc
 }
 
 func (c *pseudoConn) RemoteAddr() net.Addr {
 	return c.addr
 }
 
-func (c *pseudoConn) Write(f Frame) {
-	if atomic.LoadInt32(&c.closed) == 1 {
-		return
+func (c *pseudoConn) ConnectionState() *tls.ConnectionState {
+	return c.tls
+}
+
+func (c *pseudoConn) PaddingPolicy() PaddingPolicy {
+	return c.padding
+}
+
+func (c *pseudoConn) LocalSettings() Settings {
+	return c.settings.localSettings()
+}
+
+func (c *pseudoConn) PeerSettings() Settings {
+	return c.settings.peerSettings()
+}
+
+func (c *pseudoConn) RTT() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.rtt))
+}
+
+func (c *pseudoConn) WriteStats() WriteStats {
+	return c.framer.WriteStats()
+}
+
+// UpdateSettings records the SETTINGS frame as pending before writing it,
+// so that the ACK can't be received before it's recorded.
+// It interrupts reader to wait for the ACK until SETTINGS_TIMEOUT.
+// See: https://tools.ietf.org/html/rfc7540#section-6.5.3
+func (c *pseudoConn) UpdateSettings(ctx context.Context, settings *SettingsFrameBuilder) error {
+	f, err := settings.Build()
+	if err != nil {
+		return err
 	}
 
-	c.w <- f
+	sf, ok := f.(*SettingsFrame)
+	if !ok || sf.IsACK() {
+		return errors.New("settings to update must be SETTINGS frame without ACK")
+	}
+
+	c.settings.sent(sf, time.Now().Add(c.timeout))
+	c.source.SetReadDeadline(time.Now()) // interrupt reader to set deadline for the ACK
+
+	return c.Write(ctx, sf)
+}
+
+// updateRTT smooths round trip time with new sample in the same way as TCP.
+// See: https://tools.ietf.org/html/rfc6298#section-2
+func (c *pseudoConn) updateRTT(sample time.Duration) {
+	rtt := atomic.LoadInt64(&c.rtt)
+	if rtt == 0 {
+		rtt = int64(sample)
+	} else {
+		rtt = rtt - rtt/8 + int64(sample)/8
+	}
+
+	atomic.StoreInt64(&c.rtt, rtt)
+}
+
+// writePriority queues the frame to be written ahead of frames queued by Write.
+func (c *pseudoConn) writePriority(ctx context.Context, f Frame) {
+	select {
+	case c.priority <- f:
+	case <-ctx.Done():
+	case <-c.done:
+	}
+}
+
+// writeSettingsACK queues ACK for peer's SETTINGS.
+// Peer's new max frame size applies to frames written after the ACK, because frames built with old size may be queued ahead of it.
+// But larger size applies immediately, because frames built with it may be queued ahead of the ACK too.
+// See: https://tools.ietf.org/html/rfc7540#section-6.5.3
+func (c *pseudoConn) writeSettingsACK(ctx context.Context, framer *Framer, ack Frame) error {
+	size := c.PeerSettings().MaxFrameSize
+
+	c.sizeMu.Lock()
+	c.frameSizes = append(c.frameSizes, size)
+	if size > framer.MaxWriteFrameSize() {
+		if err := framer.SetMaxWriteFrameSize(size); err != nil {
+			c.sizeMu.Unlock()
+			return err
+		}
+	}
+	c.sizeMu.Unlock()
+
+	return c.enqueue(ctx, &outgoing{frames: []Frame{ack}, settingsACK: true})
+}
+
+// settingsACKWritten applies max frame size of peer's SETTINGS whose ACK is written.
+// Larger size of SETTINGS whose ACK isn't written yet remains applied.
+func (c *pseudoConn) settingsACKWritten(framer *Framer) error {
+	c.sizeMu.Lock()
+	defer c.sizeMu.Unlock()
+
+	if len(c.frameSizes) == 0 {
+		return nil
+	}
+
+	size := c.frameSizes[0]
+	c.frameSizes = c.frameSizes[1:]
+	for _, pending := range c.frameSizes {
+		if pending > size {
+			size = pending
+		}
+	}
+
+	return framer.SetMaxWriteFrameSize(size)
+}
+
+func (c *pseudoConn) Write(ctx context.Context, frames ...Frame) error {
+	if len(frames) == 0 {
+		return nil
+	}
+	return c.enqueue(ctx, &outgoing{frames: frames})
+}
+
+func (c *pseudoConn) Flush(ctx context.Context) error {
+	o := &outgoing{flushed: make(chan struct{})}
+	if err := c.enqueue(ctx, o); err != nil {
+		return err
+	}
+
+	select {
+	case <-o.flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.done:
+		// Writer may stop right after the flush.
+		select {
+		case <-o.flushed:
+			return nil
+		default:
+			return ErrConnClosed
+		}
+	}
+}
+
+// enqueue queues the entry. Closed connection is checked first because select chooses ready case randomly.
+func (c *pseudoConn) enqueue(ctx context.Context, o *outgoing) error {
+	select {
+	case <-c.closed:
+		return ErrConnClosed
+	default:
+	}
+
+	select {
+	case c.w <- o:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.closed:
+		return ErrConnClosed
+	case <-c.done:
+		return ErrConnClosed
+	}
 }
 
 func (c *pseudoConn) Close() {
-	if atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
-		close(c.w)
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+}
+
+// next returns a queued entry without blocking, or nil if no entry is queued.
+// Frames queued by writePriority are returned first.
+func (c *pseudoConn) next() *outgoing {
+	select {
+	case f := <-c.priority:
+		return &outgoing{frames: []Frame{f}}
+	default:
+	}
+
+	select {
+	case o := <-c.w:
+		return o
+	default:
+		return nil
 	}
 }
 
-func (c *pseudoConn) writeCh() chan Frame {
-	return c.w
+// stopped is called when writer stops, to fail Write and Flush waiting for writer.
+func (c *pseudoConn) stopped() {
+	c.doneOnce.Do(func() {
+		close(c.done)
+	})
 }
@@ -0,0 +1,55 @@
+package h2server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPseudoConn_Write(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	f, err := NewPingFrameBuilder([8]byte{}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pc := newPseudoConn(server, NewFramer(server), NoPadding(), newConnSettings(), defaultSettingsTimeout)
+	for i := 0; i < writeQueueSize; i++ {
+		if err := pc.Write(context.Background(), f); err != nil {
+			t.Fatalf("Write() got error = %v before the queue is full", err)
+		}
+	}
+
+	// Write blocks while the queue is full.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := pc.Write(ctx, f); err != context.DeadlineExceeded {
+		t.Errorf("Write() got = %v on full queue, want = %v", err, context.DeadlineExceeded)
+	}
+
+	// Write waiting for the queue fails when writer stops.
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pc.Write(context.Background(), f)
+	}()
+	pc.stopped()
+
+	select {
+	case err := <-errCh:
+		if err != ErrConnClosed {
+			t.Errorf("Write() got = %v after writer stops, want = %v", err, ErrConnClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Write() blocks after writer stops")
+	}
+
+	pc.Close()
+	pc.Close()
+	if err := pc.Write(context.Background(), f); err != ErrConnClosed {
+		t.Errorf("Write() got = %v after close, want = %v", err, ErrConnClosed)
+	}
+}
@@ -11,11 +11,28 @@ type (
 		wrapped error
 	}
 
+	// ConnectionError is error that makes the connection unusable.
+	// See: https://tools.ietf.org/html/rfc7540#section-5.4.1
+	ConnectionError struct {
+		code    ErrorCode
+		wrapped error
+	}
+
+	// StreamError is error related to a stream only. The connection is still usable.
+	// See: https://tools.ietf.org/html/rfc7540#section-5.4.2
+	StreamError struct {
+		streamID uint32
+		code     ErrorCode
+		wrapped  error
+	}
+
 	ErrorCode uint32
 )
 
 var (
 	_ error = (*H2Error)(nil)
+	_ error = (*ConnectionError)(nil)
+	_ error = (*StreamError)(nil)
 
 	UnknownFrameErr     = errors.New("unknown frame type")
 	ACKSettingsFrameErr = NewH2Error(FrameSizeError, "ack settings frame's payload length must be 0")
@@ -39,14 +56,47 @@ const (
 )
 
 func NewH2Error(code ErrorCode, format string, args ...interface{}) error {
-	var err error
+	return &H2Error{code: code, wrapped: newError(format, args...)}
+}
+
+func NewConnectionError(code ErrorCode, format string, args ...interface{}) error {
+	return &ConnectionError{code: code, wrapped: newError(format, args...)}
+}
+
+func NewStreamError(streamID uint32, code ErrorCode, format string, args ...interface{}) error {
+	return &StreamError{streamID: streamID, code: code, wrapped: newError(format, args...)}
+}
+
+func newError(format string, args ...interface{}) error {
 	if len(args) > 0 {
-		err = fmt.Errorf(format, args...)
-	} else {
-		err = errors.New(format)
+		return fmt.Errorf(format, args...)
+	}
+
+	return errors.New(format)
+}
+
+// AsConnectionError returns err as ConnectionError.
+// If err isn't ConnectionError, it's wrapped as ConnectionError with code returned by UnwrapErrorCode.
+func AsConnectionError(err error) *ConnectionError {
+	var connErr *ConnectionError
+	if errors.As(err, &connErr) {
+		return connErr
+	}
+
+	if h2Err, ok := err.(*H2Error); ok {
+		return &ConnectionError{code: h2Err.code, wrapped: h2Err.wrapped}
 	}
 
-	return &H2Error{code: code, wrapped: err}
+	return &ConnectionError{code: UnwrapErrorCode(err), wrapped: err}
+}
+
+// IsH2Error reports whether err is caused by violation of HTTP/2 rather than I/O and so on.
+func IsH2Error(err error) bool {
+	var h2Err *H2Error
+	var connErr *ConnectionError
+	var streamErr *StreamError
+
+	return errors.As(err, &h2Err) || errors.As(err, &connErr) || errors.As(err, &streamErr)
 }
 
 func UnwrapErrorCode(err error) ErrorCode {
@@ -54,8 +104,13 @@ func UnwrapErrorCode(err error) ErrorCode {
 		return NoError
 	}
 
-	if h2Error, ok := err.(*H2Error); ok {
-		return h2Error.code
+	switch h2Err := err.(type) {
+	case *H2Error:
+		return h2Err.code
+	case *ConnectionError:
+		return h2Err.code
+	case *StreamError:
+		return h2Err.code
 	}
 
 	if wrapErr, ok := err.(interface{ Unwrap() error }); ok {
@@ -82,6 +137,34 @@ func (err *H2Error) Unwrap() error {
 	return err.wrapped
 }
 
+func (err *ConnectionError) Code() ErrorCode {
+	return err.code
+}
+
+func (err *ConnectionError) Error() string {
+	return "connection error: " + err.code.String() + ": " + err.wrapped.Error()
+}
+
+func (err *ConnectionError) Unwrap() error {
+	return err.wrapped
+}
+
+func (err *StreamError) StreamID() uint32 {
+	return err.streamID
+}
+
+func (err *StreamError) Code() ErrorCode {
+	return err.code
+}
+
+func (err *StreamError) Error() string {
+	return fmt.Sprintf("stream error(%d): %s: %s", err.streamID, err.code.String(), err.wrapped.Error())
+}
+
+func (err *StreamError) Unwrap() error {
+	return err.wrapped
+}
+
 func (code ErrorCode) IsUnknown() bool {
 	return code > HTTP11RequiredError
 }
@@ -23,6 +23,14 @@ func TestUnwrapErrorCode(t *testing.T) {
 			in:   errors.New("sample"),
 			want: InternalError,
 		},
+		{
+			in:   fmt.Errorf("error: %w", NewConnectionError(FrameSizeError, "sample")),
+			want: FrameSizeError,
+		},
+		{
+			in:   NewStreamError(1, StreamClosedError, "sample"),
+			want: StreamClosedError,
+		},
 	}
 
 	for i, tt := range tests {
@@ -45,3 +53,65 @@ func TestNewUnknownFrameError(t *testing.T) {
 		t.Errorf("NewUnknownFrameError() return error is NOT UnknownFrameErr")
 	}
 }
+
+func TestAsConnectionError(t *testing.T) {
+	tests := []struct {
+		in       error
+		wantCode ErrorCode
+		wantMsg  string
+	}{
+		{
+			in:       NewH2Error(ProtocolError, "sample"),
+			wantCode: ProtocolError,
+			wantMsg:  "connection error: protocol error: sample",
+		},
+		{
+			in:       fmt.Errorf("wrapped: %w", NewConnectionError(CompressionError, "sample %d", 1)),
+			wantCode: CompressionError,
+			wantMsg:  "connection error: compression error: sample 1",
+		},
+		{
+			in:       errors.New("sample"),
+			wantCode: InternalError,
+			wantMsg:  "connection error: internal error: sample",
+		},
+	}
+
+	for i, tt := range tests {
+		got := AsConnectionError(tt.in)
+		if got.Code() != tt.wantCode || got.Error() != tt.wantMsg {
+			t.Errorf("AsConnectionError(#%d) got = (%s, %s), want = (%s, %s)", i+1, got.Code(), got.Error(), tt.wantCode, tt.wantMsg)
+		}
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", NewStreamError(3, RefusedStreamError, "sample"))
+
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) {
+		t.Fatalf("errors.As() can't find StreamError")
+	}
+
+	if streamErr.StreamID() != 3 || streamErr.Code() != RefusedStreamError || streamErr.Error() != "stream error(3): refused stream: sample" {
+		t.Errorf("StreamError got = (%d, %s, %s)", streamErr.StreamID(), streamErr.Code(), streamErr.Error())
+	}
+}
+
+func TestIsH2Error(t *testing.T) {
+	tests := []struct {
+		in   error
+		want bool
+	}{
+		{in: NewH2Error(ProtocolError, "sample"), want: true},
+		{in: fmt.Errorf("wrapped: %w", NewConnectionError(ProtocolError, "sample")), want: true},
+		{in: NewStreamError(1, ProtocolError, "sample"), want: true},
+		{in: errors.New("sample"), want: false},
+	}
+
+	for i, tt := range tests {
+		if got := IsH2Error(tt.in); got != tt.want {
+			t.Errorf("IsH2Error(#%d) got = %v, want = %v", i+1, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package h2server
+
+type (
+	// sendFlow is flow control window for sending DATA frames.
+	// It can be negative by change of SETTINGS_INITIAL_WINDOW_SIZE.
+	// See: https://tools.ietf.org/html/rfc7540#section-6.9
+	sendFlow struct {
+		window int64
+	}
+
+	// recvFlow is flow control window for receiving DATA frames.
+	// Consumed data is credited back to peer when it reaches half of the window size,
+	// so that WINDOW_UPDATE isn't sent for each DATA frame.
+	recvFlow struct {
+		window   int64
+		size     int64
+		consumed int64
+	}
+)
+
+const (
+	// See: https://tools.ietf.org/html/rfc7540#section-6.9.2
+	defaultWindowSize = (1 << 16) - 1
+)
+
+func newSendFlow(size uint32) sendFlow {
+	return sendFlow{window: int64(size)}
+}
+
+func (sf *sendFlow) available() int64 {
+	return sf.window
+}
+
+func (sf *sendFlow) take(n int64) {
+	sf.window -= n
+}
+
+// add changes the window by WINDOW_UPDATE or SETTINGS_INITIAL_WINDOW_SIZE.
+// It returns false if the window exceeds max size.
+func (sf *sendFlow) add(delta int64) bool {
+	if sf.window+delta > maxWindowSize {
+		return false
+	}
+
+	sf.window += delta
+	return true
+}
+
+func newRecvFlow(size uint32) recvFlow {
+	return recvFlow{window: int64(size), size: int64(size)}
+}
+
+// receive takes received data from the window. It returns false if peer exceeds the window.
+func (rf *recvFlow) receive(n int64) bool {
+	if n > rf.window {
+		return false
+	}
+
+	rf.window -= n
+	return true
+}
+
+// consume records that received data is consumed, and returns increment of WINDOW_UPDATE to send.
+// It returns zero if WINDOW_UPDATE isn't needed yet.
+func (rf *recvFlow) consume(n int64) uint32 {
+	rf.consumed += n
+	if rf.consumed == 0 || rf.consumed < rf.size/2 {
+		return 0
+	}
+
+	inc := rf.consumed
+	rf.window += inc
+	rf.consumed = 0
+	return uint32(inc)
+}
+
+// resize applies change of our SETTINGS_INITIAL_WINDOW_SIZE to the window.
+func (rf *recvFlow) resize(size uint32) {
+	rf.window += int64(size) - rf.size
+	rf.size = int64(size)
+}
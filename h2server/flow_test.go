@@ -0,0 +1,60 @@
+package h2server
+
+import (
+	"testing"
+)
+
+func TestSendFlow_Add(t *testing.T) {
+	tests := []struct {
+		window int64
+		delta  int64
+		want   int64
+		ok     bool
+	}{
+		{window: 0, delta: 100, want: 100, ok: true},
+		{window: 100, delta: -200, want: -100, ok: true},
+		{window: -100, delta: 200, want: 100, ok: true},
+		{window: maxWindowSize - 1, delta: 1, want: maxWindowSize, ok: true},
+		{window: maxWindowSize, delta: 1, want: maxWindowSize, ok: false},
+	}
+
+	for _, tt := range tests {
+		sf := &sendFlow{window: tt.window}
+		if ok := sf.add(tt.delta); ok != tt.ok || sf.available() != tt.want {
+			t.Errorf("add(%d) on %d got = %d, %v, want = %d, %v", tt.delta, tt.window, sf.available(), ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRecvFlow(t *testing.T) {
+	rf := newRecvFlow(100)
+
+	if !rf.receive(60) {
+		t.Fatalf("receive() got = false within window")
+	}
+
+	if rf.receive(41) {
+		t.Errorf("receive() got = true beyond window")
+	}
+
+	if inc := rf.consume(40); inc != 0 {
+		t.Errorf("consume() got = %d before half of window is consumed", inc)
+	}
+
+	if inc := rf.consume(20); inc != 60 {
+		t.Errorf("consume() got = %d, want = %d", inc, 60)
+	}
+
+	if rf.window != 100 {
+		t.Errorf("window got = %d, want = %d", rf.window, 100)
+	}
+
+	rf.resize(10)
+	if rf.window != 10 {
+		t.Errorf("window got = %d after resize, want = %d", rf.window, 10)
+	}
+
+	if !rf.receive(10) || rf.receive(1) {
+		t.Errorf("receive() doesn't respect resized window")
+	}
+}
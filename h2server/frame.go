@@ -19,6 +19,9 @@ type (
 
 	IncomingFrame interface {
 		Frame
+
+		// Verify verifies the frame. It returns StreamError if the error affects the stream only.
+		// Otherwise, the error is treated as connection error.
 		Verify() error
 	}
 
@@ -27,6 +30,7 @@ type (
 		flags    uint8
 		streamID uint32
 		payload  []byte
+		slot     *frameSlot
 	}
 
 	SettingsFrame struct {
@@ -49,30 +53,105 @@ type (
 		*frame
 	}
 
+	DataFrameBuilder struct {
+		streamID     uint32
+		data         []byte
+		endStream    bool
+		padded       bool
+		padLen       uint8
+		padding      PaddingPolicy
+		maxFrameSize uint32
+	}
+
 	HeadersFrame struct {
 		*frame
 	}
 
+	HeadersFrameBuilder struct {
+		streamID     uint32
+		block        []byte
+		endStream    bool
+		padded       bool
+		padLen       uint8
+		padding      PaddingPolicy
+		prioritized  bool
+		dependency   uint32
+		exclusive    bool
+		weight       uint16
+		maxFrameSize uint32
+	}
+
+	PriorityFrame struct {
+		*frame
+	}
+
+	PriorityFrameBuilder struct {
+		streamID   uint32
+		dependency uint32
+		exclusive  bool
+		weight     uint16
+	}
+
 	RstStreamFrame struct {
 		*frame
 	}
 
+	RstStreamFrameBuilder struct {
+		streamID uint32
+		code     ErrorCode
+	}
+
 	PushPromiseFrame struct {
 		*frame
 	}
 
+	PushPromiseFrameBuilder struct {
+		streamID         uint32
+		promisedStreamID uint32
+		block            []byte
+		padded           bool
+		padLen           uint8
+		padding          PaddingPolicy
+		maxFrameSize     uint32
+	}
+
 	PingFrame struct {
 		*frame
 	}
 
+	PingFrameBuilder struct {
+		data [8]byte
+	}
+
 	GoAwayFrame struct {
 		*frame
 	}
 
+	GoAwayFrameBuilder struct {
+		lastStreamID uint32
+		code         ErrorCode
+		debugData    []byte
+	}
+
+	WindowUpdateFrame struct {
+		*frame
+	}
+
+	WindowUpdateFrameBuilder struct {
+		streamID  uint32
+		increment uint32
+	}
+
 	ContinuationFrame struct {
 		*frame
 	}
 
+	ContinuationFrameBuilder struct {
+		streamID   uint32
+		fragment   []byte
+		endHeaders bool
+	}
+
 	UnknownFrame struct {
 		*frame
 	}
@@ -81,6 +160,11 @@ type (
 const (
 	streamIDMask = 0x7fffffff
 
+	// See: https://tools.ietf.org/html/rfc7540#section-5.3.5
+	defaultWeight = 16
+
+	maxWindowSize = (1 << 31) - 1
+
 	DataFrameType         FrameType = 0x00
 	HeadersFrameType      FrameType = 0x01
 	PriorityFrameType     FrameType = 0x02
@@ -105,58 +189,84 @@ func (typ FrameType) IsUnknown() bool {
 }
 
 func Read(r io.Reader) (IncomingFrame, error) {
-	buf := make([]byte, 9)
-	if _, err := r.Read(buf); err != nil {
+	buf := make([]byte, frameHeaderLen)
+	if _, err := io.ReadFull(r, buf); err != nil {
 		return nil, err
 	}
 
-	payloadLen := 0
-	for i := 0; i < 3; i++ {
-		payloadLen |= int(buf[i]) << ((2 - i) * 8)
-	}
+	f := &frame{}
+	f.payload = make([]byte, decodeFrameHeader(buf, f))
 
-	f := &frame{
-		typ:      FrameType(buf[3]),
-		flags:    buf[4],
-		streamID: binary.BigEndian.Uint32(buf[5:9]) & streamIDMask,
-		payload:  make([]byte, payloadLen),
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return nil, err
 	}
 
-	if _, err := r.Read(f.payload); err != nil {
-		return nil, err
+	return newIncomingFrame(f), nil
+}
+
+// decodeFrameHeader decodes frame header into f and returns payload length.
+func decodeFrameHeader(buf []byte, f *frame) uint32 {
+	var payloadLen uint32
+	for i := 0; i < 3; i++ {
+		payloadLen |= uint32(buf[i]) << ((2 - i) * 8)
 	}
 
-	var ret IncomingFrame
+	f.typ = FrameType(buf[3])
+	f.flags = buf[4]
+	f.streamID = binary.BigEndian.Uint32(buf[5:9]) & streamIDMask
+	return payloadLen
+}
+
+func newIncomingFrame(f *frame) IncomingFrame {
 	switch f.typ {
 	case DataFrameType:
-		ret = &DataFrame{frame: f}
+		return &DataFrame{frame: f}
 
 	case HeadersFrameType:
-		ret = &HeadersFrame{frame: f}
+		return &HeadersFrame{frame: f}
 
 	case PushPromiseFrameType:
-		ret = &PushPromiseFrame{frame: f}
+		return &PushPromiseFrame{frame: f}
 
 	case ContinuationFrameType:
-		ret = &ContinuationFrame{frame: f}
+		return &ContinuationFrame{frame: f}
 
 	case SettingsFrameType:
-		ret = &SettingsFrame{frame: f}
+		return &SettingsFrame{frame: f}
+
+	case PriorityFrameType:
+		return &PriorityFrame{frame: f}
 
 	case RstStreamFrameType:
-		ret = &RstStreamFrame{frame: f}
+		return &RstStreamFrame{frame: f}
 
 	case PingFrameType:
-		ret = &PingFrame{frame: f}
+		return &PingFrame{frame: f}
 
 	case GoAwayFrameType:
-		ret = &GoAwayFrame{frame: f}
+		return &GoAwayFrame{frame: f}
+
+	case WindowUpdateFrameType:
+		return &WindowUpdateFrame{frame: f}
 
 	default:
-		ret = &UnknownFrame{frame: f}
+		return &UnknownFrame{frame: f}
 	}
+}
+
+// appendFrame appends encoded frame to buf.
+func appendFrame(buf []byte, f Frame) []byte {
+	payload := f.Payload()
+	pLen := len(payload)
 
-	return ret, nil
+	buf = append(buf,
+		byte(pLen>>16), byte(pLen>>8), byte(pLen),
+		byte(f.Type()), f.Flags(),
+		0, 0, 0, 0,
+	)
+	binary.BigEndian.PutUint32(buf[len(buf)-4:], f.StreamID()&streamIDMask)
+
+	return append(buf, payload...)
 }
 
 func (la *frame) Type() FrameType {
@@ -175,24 +285,13 @@ func (la *frame) Payload() []byte {
 	return la.payload
 }
 
-func (la *frame) WriteTo(w io.Writer) (int64, error) {
-	buf := make([]byte, 9)
-	for i := 0; i < 3; i++ {
-		buf[i] = byte((len(la.payload) >> (8 * (2 - i))) & 0xff)
-	}
-	buf[3] = byte(la.typ)
-	buf[4] = la.flags
-	binary.BigEndian.PutUint32(buf[5:], la.streamID&streamIDMask)
-
-	var sum int64
-	n, err := w.Write(buf)
-	if err != nil {
-		return int64(n), err
-	}
-	sum += int64(n)
+func (la *frame) pooledSlot() *frameSlot {
+	return la.slot
+}
 
-	n, err = w.Write(la.payload)
-	return sum + int64(n), err
+func (la *frame) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(appendFrame(make([]byte, 0, frameHeaderLen+len(la.payload)), la))
+	return int64(n), err
 }
 
 func (data *DataFrame) IsEOS() bool {
@@ -228,6 +327,69 @@ func (data *DataFrame) Data() []byte {
 	return data.payload
 }
 
+func NewDataFrameBuilder(streamID uint32, data []byte) *DataFrameBuilder {
+	return &DataFrameBuilder{
+		streamID:     streamID,
+		data:         data,
+		maxFrameSize: defaultMaxFrameSize,
+	}
+}
+
+func (dfb *DataFrameBuilder) EndStream() *DataFrameBuilder {
+	dfb.endStream = true
+	return dfb
+}
+
+func (dfb *DataFrameBuilder) Padding(padLen uint8) *DataFrameBuilder {
+	dfb.padded = true
+	dfb.padLen = padLen
+	return dfb
+}
+
+// PaddingPolicy sets policy to decide length of padding. It's ignored if Padding is called.
+func (dfb *DataFrameBuilder) PaddingPolicy(policy PaddingPolicy) *DataFrameBuilder {
+	dfb.padding = policy
+	return dfb
+}
+
+// MaxFrameSize sets max frame size that peer advertised.
+// Padding decided by PaddingPolicy is limited so that the frame doesn't exceed it.
+func (dfb *DataFrameBuilder) MaxFrameSize(size uint32) *DataFrameBuilder {
+	dfb.maxFrameSize = size
+	return dfb
+}
+
+func (dfb *DataFrameBuilder) Build() (Frame, error) {
+	if dfb.streamID == 0x00 || dfb.streamID > streamIDMask {
+		return nil, NewH2Error(ProtocolError, "can't build data frame for stream(%d)", dfb.streamID)
+	}
+
+	f := &frame{
+		typ:      DataFrameType,
+		flags:    0,
+		streamID: dfb.streamID,
+		payload:  dfb.data,
+	}
+
+	if dfb.endStream {
+		f.flags |= 0x01
+	}
+
+	padLen, padded := dfb.padLen, dfb.padded
+	if !padded {
+		padLen, padded = applyPadding(dfb.padding, len(dfb.data), 0, int(dfb.maxFrameSize))
+	}
+
+	if padded {
+		f.flags |= 0x08
+		f.payload = make([]byte, 1+len(dfb.data)+int(padLen))
+		f.payload[0] = padLen
+		copy(f.payload[1:], dfb.data)
+	}
+
+	return &DataFrame{frame: f}, nil
+}
+
 func (settings *SettingsFrame) IsACK() bool {
 	return (settings.Flags() & 0x01) > 0
 }
@@ -401,8 +563,159 @@ func (headers *HeadersFrame) Fragment() []byte {
 	return headers.payload[offset:frgEnd]
 }
 
+func NewHeadersFrameBuilder(streamID uint32, block []byte) *HeadersFrameBuilder {
+	return &HeadersFrameBuilder{
+		streamID:     streamID,
+		block:        block,
+		weight:       defaultWeight,
+		maxFrameSize: defaultMaxFrameSize,
+	}
+}
+
+func (hfb *HeadersFrameBuilder) EndStream() *HeadersFrameBuilder {
+	hfb.endStream = true
+	return hfb
+}
+
+func (hfb *HeadersFrameBuilder) Padding(padLen uint8) *HeadersFrameBuilder {
+	hfb.padded = true
+	hfb.padLen = padLen
+	return hfb
+}
+
+// PaddingPolicy sets policy to decide length of padding. It's ignored if Padding is called.
+func (hfb *HeadersFrameBuilder) PaddingPolicy(policy PaddingPolicy) *HeadersFrameBuilder {
+	hfb.padding = policy
+	return hfb
+}
+
+func (hfb *HeadersFrameBuilder) Priority(dependency uint32, exclusive bool, weight uint16) *HeadersFrameBuilder {
+	hfb.prioritized = true
+	hfb.dependency = dependency
+	hfb.exclusive = exclusive
+	hfb.weight = weight
+	return hfb
+}
+
+// MaxFrameSize sets max frame size that peer advertised.
+// Header block exceeding it is split into CONTINUATION frames.
+func (hfb *HeadersFrameBuilder) MaxFrameSize(size uint32) *HeadersFrameBuilder {
+	hfb.maxFrameSize = size
+	return hfb
+}
+
+// Build builds HEADERS frame and following CONTINUATION frames if needed.
+func (hfb *HeadersFrameBuilder) Build() ([]Frame, error) {
+	if hfb.streamID == 0x00 || hfb.streamID > streamIDMask {
+		return nil, NewH2Error(ProtocolError, "can't build headers frame for stream(%d)", hfb.streamID)
+	}
+
+	var prefix []byte
+	if hfb.prioritized {
+		if hfb.dependency == hfb.streamID || hfb.dependency > streamIDMask {
+			return nil, NewH2Error(ProtocolError, "can't build headers frame depending on stream(%d)", hfb.dependency)
+		}
+
+		if hfb.weight < 1 || hfb.weight > 256 {
+			return nil, NewH2Error(ProtocolError, "can't build headers frame with weight(%d)", hfb.weight)
+		}
+
+		prefix = encodePriority(make([]byte, 0, 5), hfb.dependency, hfb.exclusive, hfb.weight)
+	}
+
+	f := &frame{
+		typ:      HeadersFrameType,
+		flags:    0,
+		streamID: hfb.streamID,
+	}
+
+	if hfb.endStream {
+		f.flags |= 0x01
+	}
+
+	if hfb.prioritized {
+		f.flags |= 0x20
+	}
+
+	padLen, padded := hfb.padLen, hfb.padded
+	if !padded {
+		padLen, padded = applyPadding(hfb.padding, firstFragmentLen(hfb.block, len(prefix), hfb.maxFrameSize), len(prefix), int(hfb.maxFrameSize))
+	}
+
+	conts, err := fragmentHeaderBlock(f, prefix, hfb.block, padded, padLen, hfb.maxFrameSize)
+	if err != nil {
+		return nil, fmt.Errorf("can't build headers frame: %w", err)
+	}
+
+	return append([]Frame{&HeadersFrame{frame: f}}, conts...), nil
+}
+
+// firstFragmentLen returns length of header block fragment fitting in the first frame with Pad Length field.
+// Padding of the first frame is decided by the fragment, because following CONTINUATION frames aren't padded.
+func firstFragmentLen(block []byte, prefixLen int, maxFrameSize uint32) int {
+	return minInt(len(block), maxInt(int(maxFrameSize)-prefixLen-1, 0))
+}
+
+// fragmentHeaderBlock sets payload of first frame(HEADERS or PUSH_PROMISE) and
+// returns CONTINUATION frames for remaining header block.
+func fragmentHeaderBlock(first *frame, prefix []byte, block []byte, padded bool, padLen uint8, maxFrameSize uint32) ([]Frame, error) {
+	if err := (&SettingsFrameParam{ID: MaxFrameSizeSetting, Value: maxFrameSize}).Verify(); err != nil {
+		return nil, err
+	}
+
+	overhead := len(prefix)
+	if padded {
+		overhead += 1 + int(padLen)
+	}
+
+	frgLen := len(block)
+	if frgLen > int(maxFrameSize)-overhead {
+		frgLen = int(maxFrameSize) - overhead
+	}
+
+	first.payload = make([]byte, 0, overhead+frgLen)
+	if padded {
+		first.flags |= 0x08
+		first.payload = append(first.payload, padLen)
+	}
+	first.payload = append(first.payload, prefix...)
+	first.payload = append(first.payload, block[:frgLen]...)
+	if padded {
+		first.payload = first.payload[:cap(first.payload)]
+	}
+
+	block = block[frgLen:]
+	if len(block) == 0 {
+		first.flags |= 0x04
+		return nil, nil
+	}
+
+	conts := make([]Frame, 0, (len(block)+int(maxFrameSize)-1)/int(maxFrameSize))
+	for len(block) > 0 {
+		frgLen = len(block)
+		if frgLen > int(maxFrameSize) {
+			frgLen = int(maxFrameSize)
+		}
+
+		cfb := NewContinuationFrameBuilder(first.streamID, block[:frgLen])
+		if frgLen == len(block) {
+			cfb.EndHeaders()
+		}
+
+		cont, err := cfb.Build()
+		if err != nil {
+			return nil, err
+		}
+
+		conts = append(conts, cont)
+		block = block[frgLen:]
+	}
+
+	return conts, nil
+}
+
 func (push *PushPromiseFrame) IsEndOfHeaders() bool {
-	return (push.flags & 0x01) > 0
+	return (push.flags & 0x04) > 0
 }
 
 func (push *PushPromiseFrame) IsPadded() bool {
@@ -451,6 +764,148 @@ func (push *PushPromiseFrame) HeaderFragment() []byte {
 	return push.payload[offset:frgEnd]
 }
 
+func (priority *PriorityFrame) Verify() error {
+	if priority.streamID == 0x00 {
+		return NewH2Error(ProtocolError, "priority frame's stream ID(0) is invalid")
+	}
+
+	pLen := len(priority.payload)
+	if pLen != 5 {
+		return NewStreamError(priority.streamID, FrameSizeError, "priority frame's payload length(%d octets) is invalid", pLen)
+	}
+
+	if priority.StreamDependency() == priority.streamID {
+		return NewStreamError(priority.streamID, ProtocolError, "priority frame's stream(%d) depends on itself", priority.streamID)
+	}
+
+	return nil
+}
+
+func (priority *PriorityFrame) StreamDependency() uint32 {
+	return binary.BigEndian.Uint32(priority.payload) & streamIDMask
+}
+
+func (priority *PriorityFrame) IsExclusive() bool {
+	return (priority.payload[0] & 0x80) > 0
+}
+
+// Weight returns weight of the stream between 1 and 256.
+func (priority *PriorityFrame) Weight() uint16 {
+	return uint16(priority.payload[4]) + 1
+}
+
+func NewPriorityFrameBuilder(streamID uint32) *PriorityFrameBuilder {
+	return &PriorityFrameBuilder{
+		streamID: streamID,
+		weight:   defaultWeight,
+	}
+}
+
+func (pfb *PriorityFrameBuilder) Dependency(streamID uint32, exclusive bool) *PriorityFrameBuilder {
+	pfb.dependency = streamID
+	pfb.exclusive = exclusive
+	return pfb
+}
+
+func (pfb *PriorityFrameBuilder) Weight(weight uint16) *PriorityFrameBuilder {
+	pfb.weight = weight
+	return pfb
+}
+
+func (pfb *PriorityFrameBuilder) Build() (Frame, error) {
+	if pfb.streamID == 0x00 || pfb.streamID > streamIDMask {
+		return nil, NewH2Error(ProtocolError, "can't build priority frame for stream(%d)", pfb.streamID)
+	}
+
+	if pfb.dependency == pfb.streamID || pfb.dependency > streamIDMask {
+		return nil, NewH2Error(ProtocolError, "can't build priority frame depending on stream(%d)", pfb.dependency)
+	}
+
+	if pfb.weight < 1 || pfb.weight > 256 {
+		return nil, NewH2Error(ProtocolError, "can't build priority frame with weight(%d)", pfb.weight)
+	}
+
+	return &PriorityFrame{
+		frame: &frame{
+			typ:      PriorityFrameType,
+			flags:    0,
+			streamID: pfb.streamID,
+			payload:  encodePriority(make([]byte, 0, 5), pfb.dependency, pfb.exclusive, pfb.weight),
+		},
+	}, nil
+}
+
+func encodePriority(buf []byte, dependency uint32, exclusive bool, weight uint16) []byte {
+	if exclusive {
+		dependency |= 0x80000000
+	}
+
+	return append(buf,
+		byte(dependency>>24), byte(dependency>>16), byte(dependency>>8), byte(dependency),
+		byte(weight-1),
+	)
+}
+
+func NewPushPromiseFrameBuilder(streamID uint32, promisedStreamID uint32, block []byte) *PushPromiseFrameBuilder {
+	return &PushPromiseFrameBuilder{
+		streamID:         streamID,
+		promisedStreamID: promisedStreamID,
+		block:            block,
+		maxFrameSize:     defaultMaxFrameSize,
+	}
+}
+
+func (pfb *PushPromiseFrameBuilder) Padding(padLen uint8) *PushPromiseFrameBuilder {
+	pfb.padded = true
+	pfb.padLen = padLen
+	return pfb
+}
+
+// PaddingPolicy sets policy to decide length of padding. It's ignored if Padding is called.
+func (pfb *PushPromiseFrameBuilder) PaddingPolicy(policy PaddingPolicy) *PushPromiseFrameBuilder {
+	pfb.padding = policy
+	return pfb
+}
+
+// MaxFrameSize sets max frame size that peer advertised.
+// Header block exceeding it is split into CONTINUATION frames.
+func (pfb *PushPromiseFrameBuilder) MaxFrameSize(size uint32) *PushPromiseFrameBuilder {
+	pfb.maxFrameSize = size
+	return pfb
+}
+
+// Build builds PUSH_PROMISE frame and following CONTINUATION frames if needed.
+func (pfb *PushPromiseFrameBuilder) Build() ([]Frame, error) {
+	if pfb.streamID == 0x00 || pfb.streamID > streamIDMask {
+		return nil, NewH2Error(ProtocolError, "can't build push promise frame for stream(%d)", pfb.streamID)
+	}
+
+	if pfb.promisedStreamID == 0x00 || pfb.promisedStreamID > streamIDMask {
+		return nil, NewH2Error(ProtocolError, "can't build push promise frame promising stream(%d)", pfb.promisedStreamID)
+	}
+
+	f := &frame{
+		typ:      PushPromiseFrameType,
+		flags:    0,
+		streamID: pfb.streamID,
+	}
+
+	prefix := make([]byte, 4)
+	binary.BigEndian.PutUint32(prefix, pfb.promisedStreamID)
+
+	padLen, padded := pfb.padLen, pfb.padded
+	if !padded {
+		padLen, padded = applyPadding(pfb.padding, firstFragmentLen(pfb.block, len(prefix), pfb.maxFrameSize), len(prefix), int(pfb.maxFrameSize))
+	}
+
+	conts, err := fragmentHeaderBlock(f, prefix, pfb.block, padded, padLen, pfb.maxFrameSize)
+	if err != nil {
+		return nil, fmt.Errorf("can't build push promise frame: %w", err)
+	}
+
+	return append([]Frame{&PushPromiseFrame{frame: f}}, conts...), nil
+}
+
 func (rst *RstStreamFrame) Verify() error {
 	if rst.streamID == 0x00 {
 		return NewH2Error(ProtocolError, "rst frame's stream ID(0) is invalid")
@@ -468,6 +923,29 @@ func (rst *RstStreamFrame) ErrorCode() ErrorCode {
 	return ErrorCode(binary.BigEndian.Uint32(rst.payload))
 }
 
+func NewRstStreamFrameBuilder(streamID uint32, code ErrorCode) *RstStreamFrameBuilder {
+	return &RstStreamFrameBuilder{
+		streamID: streamID,
+		code:     code,
+	}
+}
+
+func (rfb *RstStreamFrameBuilder) Build() (Frame, error) {
+	if rfb.streamID == 0x00 || rfb.streamID > streamIDMask {
+		return nil, NewH2Error(ProtocolError, "can't build rst stream frame for stream(%d)", rfb.streamID)
+	}
+
+	f := &frame{
+		typ:      RstStreamFrameType,
+		flags:    0,
+		streamID: rfb.streamID,
+		payload:  make([]byte, 4),
+	}
+	binary.BigEndian.PutUint32(f.payload, uint32(rfb.code))
+
+	return &RstStreamFrame{frame: f}, nil
+}
+
 func (ping *PingFrame) IsACK() bool {
 	return (ping.flags & 0x01) > 0
 }
@@ -485,17 +963,40 @@ func (ping *PingFrame) Verify() error {
 	return nil
 }
 
+// Data returns opaque data of the frame.
+func (ping *PingFrame) Data() [8]byte {
+	var data [8]byte
+	copy(data[:], ping.payload)
+	return data
+}
+
 func (ping *PingFrame) ACK() (Frame, error) {
 	if ping.IsACK() {
 		return nil, NewH2Error(InternalError, "can't ack to ack ping frame")
 	}
 
+	// Payload is copied because it may refer to read buffer of Framer.
 	return &PingFrame{
 		frame: &frame{
 			typ:      PingFrameType,
 			flags:    0x01,
 			streamID: 0,
-			payload:  ping.payload,
+			payload:  append([]byte(nil), ping.payload...),
+		},
+	}, nil
+}
+
+func NewPingFrameBuilder(data [8]byte) *PingFrameBuilder {
+	return &PingFrameBuilder{data: data}
+}
+
+func (pfb *PingFrameBuilder) Build() (Frame, error) {
+	return &PingFrame{
+		frame: &frame{
+			typ:      PingFrameType,
+			flags:    0,
+			streamID: 0,
+			payload:  append([]byte(nil), pfb.data[:]...),
 		},
 	}, nil
 }
@@ -516,6 +1017,37 @@ func (cont *ContinuationFrame) Fragment() []byte {
 	return cont.payload
 }
 
+func NewContinuationFrameBuilder(streamID uint32, fragment []byte) *ContinuationFrameBuilder {
+	return &ContinuationFrameBuilder{
+		streamID: streamID,
+		fragment: fragment,
+	}
+}
+
+func (cfb *ContinuationFrameBuilder) EndHeaders() *ContinuationFrameBuilder {
+	cfb.endHeaders = true
+	return cfb
+}
+
+func (cfb *ContinuationFrameBuilder) Build() (Frame, error) {
+	if cfb.streamID == 0x00 || cfb.streamID > streamIDMask {
+		return nil, NewH2Error(ProtocolError, "can't build continuation frame for stream(%d)", cfb.streamID)
+	}
+
+	f := &frame{
+		typ:      ContinuationFrameType,
+		flags:    0,
+		streamID: cfb.streamID,
+		payload:  cfb.fragment,
+	}
+
+	if cfb.endHeaders {
+		f.flags |= 0x04
+	}
+
+	return &ContinuationFrame{frame: f}, nil
+}
+
 func (goAway *GoAwayFrame) Verify() error {
 	if goAway.streamID != 0x00 {
 		return NewH2Error(ProtocolError, "go away frame's stream ID must be 0x00")
@@ -541,6 +1073,83 @@ func (goAway *GoAwayFrame) DebugData() []byte {
 	return goAway.payload[8:]
 }
 
+func NewGoAwayFrameBuilder(lastStreamID uint32, code ErrorCode) *GoAwayFrameBuilder {
+	return &GoAwayFrameBuilder{
+		lastStreamID: lastStreamID,
+		code:         code,
+	}
+}
+
+func (gfb *GoAwayFrameBuilder) DebugData(data []byte) *GoAwayFrameBuilder {
+	gfb.debugData = data
+	return gfb
+}
+
+func (gfb *GoAwayFrameBuilder) Build() (Frame, error) {
+	if gfb.lastStreamID > streamIDMask {
+		return nil, NewH2Error(ProtocolError, "can't build go away frame with last stream(%d)", gfb.lastStreamID)
+	}
+
+	f := &frame{
+		typ:      GoAwayFrameType,
+		flags:    0,
+		streamID: 0,
+		payload:  make([]byte, 8+len(gfb.debugData)),
+	}
+	binary.BigEndian.PutUint32(f.payload, gfb.lastStreamID)
+	binary.BigEndian.PutUint32(f.payload[4:], uint32(gfb.code))
+	copy(f.payload[8:], gfb.debugData)
+
+	return &GoAwayFrame{frame: f}, nil
+}
+
+func (winUpdate *WindowUpdateFrame) Verify() error {
+	pLen := len(winUpdate.payload)
+	if pLen != 4 {
+		return NewH2Error(FrameSizeError, "window update frame's payload length(%d octets) is invalid", pLen)
+	}
+
+	if winUpdate.WindowSizeIncrement() == 0 {
+		if winUpdate.streamID == 0x00 {
+			return NewH2Error(ProtocolError, "window update frame's increment for connection must not be 0")
+		}
+		return NewStreamError(winUpdate.streamID, ProtocolError, "window update frame's increment for stream(%d) must not be 0", winUpdate.streamID)
+	}
+
+	return nil
+}
+
+func (winUpdate *WindowUpdateFrame) WindowSizeIncrement() uint32 {
+	return binary.BigEndian.Uint32(winUpdate.payload) & streamIDMask
+}
+
+func NewWindowUpdateFrameBuilder(streamID uint32, increment uint32) *WindowUpdateFrameBuilder {
+	return &WindowUpdateFrameBuilder{
+		streamID:  streamID,
+		increment: increment,
+	}
+}
+
+func (wfb *WindowUpdateFrameBuilder) Build() (Frame, error) {
+	if wfb.streamID > streamIDMask {
+		return nil, NewH2Error(ProtocolError, "can't build window update frame for stream(%d)", wfb.streamID)
+	}
+
+	if wfb.increment == 0 || wfb.increment > maxWindowSize {
+		return nil, NewH2Error(FlowControlError, "can't build window update frame with increment(%d)", wfb.increment)
+	}
+
+	f := &frame{
+		typ:      WindowUpdateFrameType,
+		flags:    0,
+		streamID: wfb.streamID,
+		payload:  make([]byte, 4),
+	}
+	binary.BigEndian.PutUint32(f.payload, wfb.increment)
+
+	return &WindowUpdateFrame{frame: f}, nil
+}
+
 func (unknown *UnknownFrame) Verify() error {
 	return nil
 }
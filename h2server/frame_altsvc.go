@@ -0,0 +1,110 @@
+package h2server
+
+import (
+	"encoding/binary"
+)
+
+type (
+	// AltSvcFrame is ALTSVC frame to advertise alternative services.
+	// See: https://tools.ietf.org/html/rfc7838#section-4
+	AltSvcFrame struct {
+		*frame
+	}
+
+	AltSvcFrameBuilder struct {
+		streamID   uint32
+		origin     string
+		fieldValue string
+	}
+)
+
+const (
+	AltSvcFrameType FrameType = 0x0a
+)
+
+var (
+	_ IncomingFrame = (*AltSvcFrame)(nil)
+	_ FrameDecoder  = DecodeAltSvcFrame
+)
+
+// DecodeAltSvcFrame is FrameDecoder for ALTSVC frame.
+// Server must ignore ALTSVC frame, so it's useful for client or testing only.
+func DecodeAltSvcFrame(unknown *UnknownFrame) (IncomingFrame, error) {
+	return &AltSvcFrame{frame: unknown.frame}, nil
+}
+
+// Verify verifies ALTSVC frame.
+// Receiver should ignore ALTSVC frame that this method reports as invalid rather than treating it as connection error.
+func (altSvc *AltSvcFrame) Verify() error {
+	pLen := len(altSvc.payload)
+	if pLen < 2 || pLen < 2+altSvc.originLen() {
+		return NewH2Error(FrameSizeError, "alt svc frame's payload length(%d octets) is invalid", pLen)
+	}
+
+	if altSvc.streamID == 0x00 && altSvc.originLen() == 0 {
+		return NewH2Error(ProtocolError, "alt svc frame on stream ID(0) must have origin")
+	}
+
+	if altSvc.streamID != 0x00 && altSvc.originLen() > 0 {
+		return NewH2Error(ProtocolError, "alt svc frame on stream ID(%d) must not have origin", altSvc.streamID)
+	}
+
+	return nil
+}
+
+func (altSvc *AltSvcFrame) originLen() int {
+	return int(binary.BigEndian.Uint16(altSvc.payload))
+}
+
+func (altSvc *AltSvcFrame) Origin() string {
+	return string(altSvc.payload[2 : 2+altSvc.originLen()])
+}
+
+// FieldValue returns value of Alt-Svc header field.
+// See: https://tools.ietf.org/html/rfc7838#section-3
+func (altSvc *AltSvcFrame) FieldValue() string {
+	return string(altSvc.payload[2+altSvc.originLen():])
+}
+
+// NewAltSvcFrameBuilder returns builder of ALTSVC frame on stream ID(0).
+func NewAltSvcFrameBuilder(origin string, fieldValue string) *AltSvcFrameBuilder {
+	return &AltSvcFrameBuilder{
+		streamID:   0,
+		origin:     origin,
+		fieldValue: fieldValue,
+	}
+}
+
+// StreamID sets stream the frame applies to. Origin must be empty in this case.
+func (afb *AltSvcFrameBuilder) StreamID(streamID uint32) *AltSvcFrameBuilder {
+	afb.streamID = streamID
+	return afb
+}
+
+func (afb *AltSvcFrameBuilder) Build() (Frame, error) {
+	if afb.streamID > streamIDMask {
+		return nil, NewH2Error(ProtocolError, "can't build alt svc frame for stream(%d)", afb.streamID)
+	}
+
+	oLen := len(afb.origin)
+	if oLen > 0xffff {
+		return nil, NewH2Error(FrameSizeError, "can't build alt svc frame with too long origin(%d octets)", oLen)
+	}
+
+	f := &frame{
+		typ:      AltSvcFrameType,
+		flags:    0,
+		streamID: afb.streamID,
+		payload:  make([]byte, 2, 2+oLen+len(afb.fieldValue)),
+	}
+	binary.BigEndian.PutUint16(f.payload, uint16(oLen))
+	f.payload = append(f.payload, afb.origin...)
+	f.payload = append(f.payload, afb.fieldValue...)
+
+	altSvc := &AltSvcFrame{frame: f}
+	if err := altSvc.Verify(); err != nil {
+		return nil, err
+	}
+
+	return altSvc, nil
+}
@@ -0,0 +1,129 @@
+package h2server
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAltSvcFrame_Verify(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame *frame
+		want  ErrorCode
+	}{
+		{
+			name:  "stream-0-with-origin",
+			frame: &frame{typ: AltSvcFrameType, streamID: 0, payload: []byte{0x00, 0x01, 'a', 'h'}},
+			want:  NoError,
+		},
+		{
+			name:  "stream-1-without-origin",
+			frame: &frame{typ: AltSvcFrameType, streamID: 1, payload: []byte{0x00, 0x00, 'h'}},
+			want:  NoError,
+		},
+		{
+			name:  "stream-0-without-origin",
+			frame: &frame{typ: AltSvcFrameType, streamID: 0, payload: []byte{0x00, 0x00, 'h'}},
+			want:  ProtocolError,
+		},
+		{
+			name:  "stream-1-with-origin",
+			frame: &frame{typ: AltSvcFrameType, streamID: 1, payload: []byte{0x00, 0x01, 'a', 'h'}},
+			want:  ProtocolError,
+		},
+		{
+			name:  "too-short",
+			frame: &frame{typ: AltSvcFrameType, streamID: 0, payload: []byte{0x00}},
+			want:  FrameSizeError,
+		},
+		{
+			name:  "too-long-origin-len",
+			frame: &frame{typ: AltSvcFrameType, streamID: 0, payload: []byte{0x00, 0x05, 'a'}},
+			want:  FrameSizeError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UnwrapErrorCode((&AltSvcFrame{frame: tt.frame}).Verify())
+			if got != tt.want {
+				t.Errorf("Verify() got = %s, want = %s", got.String(), tt.want.String())
+			}
+		})
+	}
+}
+
+func TestAltSvcFrameBuilder_Build(t *testing.T) {
+	type want struct {
+		frame      *frame
+		origin     string
+		fieldValue string
+		err        ErrorCode
+	}
+
+	tests := []struct {
+		name    string
+		builder *AltSvcFrameBuilder
+		want    want
+	}{
+		{
+			name:    "origin",
+			builder: NewAltSvcFrameBuilder("https://a.example", `h2="b.example:443"`),
+			want: want{
+				frame: &frame{
+					typ:      AltSvcFrameType,
+					streamID: 0,
+					payload:  append([]byte{0x00, 0x11}, `https://a.example`+`h2="b.example:443"`...),
+				},
+				origin:     "https://a.example",
+				fieldValue: `h2="b.example:443"`,
+			},
+		},
+		{
+			name:    "stream",
+			builder: NewAltSvcFrameBuilder("", "clear").StreamID(3),
+			want: want{
+				frame: &frame{
+					typ:      AltSvcFrameType,
+					streamID: 3,
+					payload:  append([]byte{0x00, 0x00}, "clear"...),
+				},
+				fieldValue: "clear",
+			},
+		},
+		{
+			name:    "stream-0-without-origin",
+			builder: NewAltSvcFrameBuilder("", "clear"),
+			want:    want{err: ProtocolError},
+		},
+		{
+			name:    "stream-with-origin",
+			builder: NewAltSvcFrameBuilder("https://a.example", "clear").StreamID(3),
+			want:    want{err: ProtocolError},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotErr := tt.builder.Build()
+
+			errCode := UnwrapErrorCode(gotErr)
+			if errCode != tt.want.err {
+				t.Errorf("Build() got = %s, want = %v", errCode.String(), tt.want.err.String())
+			}
+			if gotErr != nil {
+				return
+			}
+
+			w := &AltSvcFrame{frame: tt.want.frame}
+			if !reflect.DeepEqual(got, w) {
+				t.Errorf("Build() got = %+v, want = %+v", got, w)
+			}
+
+			altSvc := got.(*AltSvcFrame)
+			if altSvc.Origin() != tt.want.origin || altSvc.FieldValue() != tt.want.fieldValue {
+				t.Errorf("Origin(), FieldValue() got = %s, %s", altSvc.Origin(), altSvc.FieldValue())
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package h2server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type (
+	// OriginFrame is ORIGIN frame to indicate origins the server is authoritative for.
+	// See: https://tools.ietf.org/html/rfc8336#section-2
+	OriginFrame struct {
+		*frame
+	}
+
+	OriginFrameBuilder struct {
+		origins []string
+	}
+)
+
+const (
+	OriginFrameType FrameType = 0x0c
+)
+
+var (
+	_ IncomingFrame = (*OriginFrame)(nil)
+	_ FrameDecoder  = DecodeOriginFrame
+)
+
+// DecodeOriginFrame is FrameDecoder for ORIGIN frame.
+// Server must ignore ORIGIN frame, so it's useful for client or testing only.
+func DecodeOriginFrame(unknown *UnknownFrame) (IncomingFrame, error) {
+	return &OriginFrame{frame: unknown.frame}, nil
+}
+
+// Verify verifies ORIGIN frame.
+// Receiver should ignore ORIGIN frame that this method reports as invalid rather than treating it as connection error.
+func (origin *OriginFrame) Verify() error {
+	if origin.streamID != 0x00 {
+		return NewH2Error(ProtocolError, "origin frame's stream ID must be 0x00")
+	}
+
+	for offset := 0; offset < len(origin.payload); {
+		if len(origin.payload)-offset < 2 {
+			return NewH2Error(FrameSizeError, "origin frame's payload length is invalid")
+		}
+
+		offset += 2 + int(binary.BigEndian.Uint16(origin.payload[offset:]))
+		if offset > len(origin.payload) {
+			return NewH2Error(FrameSizeError, "origin frame's payload length is invalid")
+		}
+	}
+
+	return nil
+}
+
+func (origin *OriginFrame) Origins() []string {
+	origins := make([]string, 0)
+	for offset := 0; offset+2 <= len(origin.payload); {
+		oLen := int(binary.BigEndian.Uint16(origin.payload[offset:]))
+		offset += 2
+		if offset+oLen > len(origin.payload) {
+			break
+		}
+
+		origins = append(origins, string(origin.payload[offset:offset+oLen]))
+		offset += oLen
+	}
+
+	return origins
+}
+
+func NewOriginFrameBuilder() *OriginFrameBuilder {
+	return &OriginFrameBuilder{
+		origins: make([]string, 0),
+	}
+}
+
+func (ofb *OriginFrameBuilder) Add(origins ...string) *OriginFrameBuilder {
+	ofb.origins = append(ofb.origins, origins...)
+	return ofb
+}
+
+func (ofb *OriginFrameBuilder) Build() (Frame, error) {
+	f := &frame{
+		typ:      OriginFrameType,
+		flags:    0,
+		streamID: 0,
+		payload:  make([]byte, 0),
+	}
+
+	for _, o := range ofb.origins {
+		if len(o) == 0 || len(o) > 0xffff {
+			return nil, NewH2Error(FrameSizeError, "can't build origin frame with origin(%d octets)", len(o))
+		}
+
+		f.payload = append(f.payload, byte(len(o)>>8), byte(len(o)))
+		f.payload = append(f.payload, o...)
+	}
+
+	return &OriginFrame{frame: f}, nil
+}
+
+// OriginsFromCertificate returns origins that the certificate is valid for.
+// Wildcard names are skipped because ORIGIN frame can't represent them.
+func OriginsFromCertificate(cert tls.Certificate) ([]string, error) {
+	leaf := cert.Leaf
+	if leaf == nil {
+		if len(cert.Certificate) == 0 {
+			return nil, errors.New("certificate is empty")
+		}
+
+		var err error
+		if leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+	}
+
+	origins := make([]string, 0, len(leaf.DNSNames)+len(leaf.IPAddresses))
+	for _, name := range leaf.DNSNames {
+		if strings.Contains(name, "*") {
+			continue
+		}
+		origins = append(origins, "https://"+strings.ToLower(name))
+	}
+
+	for _, ip := range leaf.IPAddresses {
+		if ip.To4() == nil {
+			origins = append(origins, "https://["+ip.String()+"]")
+			continue
+		}
+		origins = append(origins, "https://"+ip.String())
+	}
+
+	return origins, nil
+}
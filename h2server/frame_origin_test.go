@@ -0,0 +1,132 @@
+package h2server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestCertificate(t *testing.T, hosts ...string) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "h2server test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestOriginFrame_Verify(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame *frame
+		want  ErrorCode
+	}{
+		{
+			name:  "empty",
+			frame: &frame{typ: OriginFrameType, streamID: 0, payload: []byte{}},
+			want:  NoError,
+		},
+		{
+			name:  "origins",
+			frame: &frame{typ: OriginFrameType, streamID: 0, payload: []byte{0x00, 0x01, 'a', 0x00, 0x02, 'b', 'c'}},
+			want:  NoError,
+		},
+		{
+			name:  "non-zero-stream-id",
+			frame: &frame{typ: OriginFrameType, streamID: 1, payload: []byte{}},
+			want:  ProtocolError,
+		},
+		{
+			name:  "truncated-length",
+			frame: &frame{typ: OriginFrameType, streamID: 0, payload: []byte{0x00, 0x01, 'a', 0x00}},
+			want:  FrameSizeError,
+		},
+		{
+			name:  "truncated-origin",
+			frame: &frame{typ: OriginFrameType, streamID: 0, payload: []byte{0x00, 0x03, 'a'}},
+			want:  FrameSizeError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UnwrapErrorCode((&OriginFrame{frame: tt.frame}).Verify())
+			if got != tt.want {
+				t.Errorf("Verify() got = %s, want = %s", got.String(), tt.want.String())
+			}
+		})
+	}
+}
+
+func TestOriginFrameBuilder_Build(t *testing.T) {
+	got, err := NewOriginFrameBuilder().Add("https://a.example", "https://b.example").Build()
+	if err != nil {
+		t.Fatalf("Build() got error = %v", err)
+	}
+
+	want := &OriginFrame{
+		frame: &frame{
+			typ:      OriginFrameType,
+			streamID: 0,
+			payload:  append(append([]byte{0x00, 0x11}, "https://a.example"...), append([]byte{0x00, 0x11}, "https://b.example"...)...),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Build() got = %+v, want = %+v", got, want)
+	}
+
+	origins := got.(*OriginFrame).Origins()
+	if !reflect.DeepEqual(origins, []string{"https://a.example", "https://b.example"}) {
+		t.Errorf("Origins() got = %v", origins)
+	}
+
+	if _, err := NewOriginFrameBuilder().Add("").Build(); UnwrapErrorCode(err) != FrameSizeError {
+		t.Errorf("Build() with empty origin got error = %v", err)
+	}
+}
+
+func TestOriginsFromCertificate(t *testing.T) {
+	cert := newTestCertificate(t, "a.example", "*.b.example", "C.example", "127.0.0.1", "::1")
+
+	got, err := OriginsFromCertificate(cert)
+	if err != nil {
+		t.Fatalf("OriginsFromCertificate() got error = %v", err)
+	}
+
+	want := []string{"https://a.example", "https://c.example", "https://127.0.0.1", "https://[::1]"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("OriginsFromCertificate() got = %v, want = %v", got, want)
+	}
+}
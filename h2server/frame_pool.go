@@ -0,0 +1,107 @@
+package h2server
+
+import "sync"
+
+type (
+	// frameSlot holds a frame and its typed representations to decode frames without allocation.
+	frameSlot struct {
+		f         frame
+		buf       []byte
+		data      DataFrame
+		headers   HeadersFrame
+		priority  PriorityFrame
+		rst       RstStreamFrame
+		settings  SettingsFrame
+		push      PushPromiseFrame
+		ping      PingFrame
+		goAway    GoAwayFrame
+		winUpdate WindowUpdateFrame
+		cont      ContinuationFrame
+		unknown   UnknownFrame
+	}
+
+	pooledFrame interface {
+		pooledSlot() *frameSlot
+	}
+)
+
+var (
+	frameSlotPool = sync.Pool{
+		New: func() interface{} {
+			return newFrameSlot()
+		},
+	}
+)
+
+func newFrameSlot() *frameSlot {
+	slot := &frameSlot{}
+	slot.f.slot = slot
+
+	slot.data.frame = &slot.f
+	slot.headers.frame = &slot.f
+	slot.priority.frame = &slot.f
+	slot.rst.frame = &slot.f
+	slot.settings.frame = &slot.f
+	slot.push.frame = &slot.f
+	slot.ping.frame = &slot.f
+	slot.goAway.frame = &slot.f
+	slot.winUpdate.frame = &slot.f
+	slot.cont.frame = &slot.f
+	slot.unknown.frame = &slot.f
+
+	return slot
+}
+
+func acquireFrameSlot() *frameSlot {
+	return frameSlotPool.Get().(*frameSlot)
+}
+
+func (slot *frameSlot) release() {
+	slot.f.payload = nil
+	frameSlotPool.Put(slot)
+}
+
+// payloadBuffer returns buffer of the slot to read payload of n octets.
+func (slot *frameSlot) payloadBuffer(n uint32) []byte {
+	if uint32(cap(slot.buf)) < n {
+		slot.buf = make([]byte, n)
+	}
+	return slot.buf[:n]
+}
+
+func (slot *frameSlot) incoming() IncomingFrame {
+	switch slot.f.typ {
+	case DataFrameType:
+		return &slot.data
+
+	case HeadersFrameType:
+		return &slot.headers
+
+	case PriorityFrameType:
+		return &slot.priority
+
+	case RstStreamFrameType:
+		return &slot.rst
+
+	case SettingsFrameType:
+		return &slot.settings
+
+	case PushPromiseFrameType:
+		return &slot.push
+
+	case PingFrameType:
+		return &slot.ping
+
+	case GoAwayFrameType:
+		return &slot.goAway
+
+	case WindowUpdateFrameType:
+		return &slot.winUpdate
+
+	case ContinuationFrameType:
+		return &slot.cont
+
+	default:
+		return &slot.unknown
+	}
+}
@@ -0,0 +1,104 @@
+package h2server
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+type (
+	// FrameDecoder decodes extension frame from frame received as unknown type.
+	// Payload of the frame refers to read buffer of Framer, so decoder must copy it to retain.
+	FrameDecoder func(*UnknownFrame) (IncomingFrame, error)
+
+	// FrameRegistry holds decoders for extension frame types.
+	// Frames of type not registered are decoded as UnknownFrame and must be ignored.
+	// See: https://tools.ietf.org/html/rfc7540#section-4.1
+	//      https://tools.ietf.org/html/rfc7540#section-5.5
+	FrameRegistry struct {
+		mu       sync.RWMutex
+		decoders map[FrameType]FrameDecoder
+	}
+
+	// ExtensionFrameError is error of decoder for extension frame.
+	// The frame has been read entirely, so the connection is still usable by ignoring the frame.
+	ExtensionFrameError struct {
+		typ     FrameType
+		wrapped error
+	}
+)
+
+var (
+	_ error = (*ExtensionFrameError)(nil)
+)
+
+func NewFrameRegistry() *FrameRegistry {
+	return &FrameRegistry{
+		decoders: make(map[FrameType]FrameDecoder),
+	}
+}
+
+func (reg *FrameRegistry) Register(typ FrameType, decoder FrameDecoder) error {
+	if !typ.IsUnknown() {
+		return fmt.Errorf("can't register decoder for frame type(0x%X) defined by HTTP/2", typ)
+	}
+
+	if decoder == nil {
+		return fmt.Errorf("can't register nil decoder for frame type(0x%X)", typ)
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if _, ok := reg.decoders[typ]; ok {
+		return fmt.Errorf("decoder for frame type(0x%X) is already registered", typ)
+	}
+
+	reg.decoders[typ] = decoder
+	return nil
+}
+
+func (reg *FrameRegistry) IsRegistered(typ FrameType) bool {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	_, ok := reg.decoders[typ]
+	return ok
+}
+
+// decode decodes unknown frame with registered decoder.
+// If decoder for the type isn't registered, unknown frame is returned as it is.
+// Error of decoder is returned as ExtensionFrameError unless it's ConnectionError explicitly.
+func (reg *FrameRegistry) decode(unknown *UnknownFrame) (IncomingFrame, error) {
+	reg.mu.RLock()
+	decoder, ok := reg.decoders[unknown.typ]
+	reg.mu.RUnlock()
+
+	if !ok {
+		return unknown, nil
+	}
+
+	decoded, err := decoder(unknown)
+	if err != nil {
+		var connErr *ConnectionError
+		if errors.As(err, &connErr) {
+			return nil, fmt.Errorf("failed to decode frame type(0x%X): %w", unknown.typ, err)
+		}
+		return nil, &ExtensionFrameError{typ: unknown.typ, wrapped: err}
+	}
+
+	return decoded, nil
+}
+
+func (e *ExtensionFrameError) Error() string {
+	return fmt.Sprintf("failed to decode frame type(0x%X): %s", e.typ, e.wrapped.Error())
+}
+
+func (e *ExtensionFrameError) Unwrap() error {
+	return e.wrapped
+}
+
+// Type returns type of the frame failed to decode.
+func (e *ExtensionFrameError) Type() FrameType {
+	return e.typ
+}
@@ -0,0 +1,98 @@
+package h2server
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type testExtensionFrame struct {
+	*UnknownFrame
+	value byte
+}
+
+func TestFrameRegistry_Register(t *testing.T) {
+	decoder := func(unknown *UnknownFrame) (IncomingFrame, error) {
+		return unknown, nil
+	}
+
+	tests := []struct {
+		name    string
+		typ     FrameType
+		decoder FrameDecoder
+		wantErr bool
+	}{
+		{name: "extension", typ: 0x0a, decoder: decoder, wantErr: false},
+		{name: "duplicated", typ: 0x0a, decoder: decoder, wantErr: true},
+		{name: "core", typ: ContinuationFrameType, decoder: decoder, wantErr: true},
+		{name: "nil", typ: 0xFF, decoder: nil, wantErr: true},
+	}
+
+	reg := NewFrameRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := reg.Register(tt.typ, tt.decoder)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Register() got error = %v, want error = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFramer_ReadFrame_WithRegistry(t *testing.T) {
+	reg := NewFrameRegistry()
+	err := reg.Register(0xF0, func(unknown *UnknownFrame) (IncomingFrame, error) {
+		if len(unknown.Payload()) != 1 {
+			return nil, NewH2Error(FrameSizeError, "invalid payload")
+		}
+		return &testExtensionFrame{UnknownFrame: unknown, value: unknown.Payload()[0]}, nil
+	})
+	if err != nil {
+		t.Fatalf("Register() got error = %v", err)
+	}
+
+	err = reg.Register(0xF2, func(unknown *UnknownFrame) (IncomingFrame, error) {
+		return nil, NewConnectionError(ProtocolError, "fatal extension frame")
+	})
+	if err != nil {
+		t.Fatalf("Register() got error = %v", err)
+	}
+
+	in := []byte{
+		0x00, 0x00, 0x01, 0xF0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x7B, // registered
+		0x00, 0x00, 0x01, 0xF1, 0x00, 0x00, 0x00, 0x00, 0x00, 0x7B, // not registered
+		0x00, 0x00, 0x00, 0xF0, 0x00, 0x00, 0x00, 0x00, 0x00, // registered, but invalid
+		0x00, 0x00, 0x01, 0xF0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x7C, // registered, after invalid one
+		0x00, 0x00, 0x00, 0xF2, 0x00, 0x00, 0x00, 0x00, 0x00, // connection error
+	}
+
+	fr := NewFramer(readWriter{Reader: bytes.NewReader(in)})
+	fr.SetFrameRegistry(reg)
+
+	got, err := fr.ReadFrame()
+	if ext, ok := got.(*testExtensionFrame); err != nil || !ok || ext.value != 0x7B {
+		t.Errorf("ReadFrame() got = %+v, error = %v", got, err)
+	}
+
+	got, err = fr.ReadFrame()
+	if _, ok := got.(*UnknownFrame); err != nil || !ok {
+		t.Errorf("ReadFrame() got = %+v, error = %v", got, err)
+	}
+
+	var extErr *ExtensionFrameError
+	_, err = fr.ReadFrame()
+	if !errors.As(err, &extErr) || extErr.Type() != 0xF0 || UnwrapErrorCode(err) != FrameSizeError {
+		t.Errorf("ReadFrame() got error = %v, want ExtensionFrameError", err)
+	}
+
+	got, err = fr.ReadFrame()
+	if ext, ok := got.(*testExtensionFrame); err != nil || !ok || ext.value != 0x7C {
+		t.Errorf("ReadFrame() after invalid frame got = %+v, error = %v", got, err)
+	}
+
+	var connErr *ConnectionError
+	_, err = fr.ReadFrame()
+	if !errors.As(err, &connErr) || errors.As(err, &extErr) {
+		t.Errorf("ReadFrame() got error = %v, want ConnectionError", err)
+	}
+}
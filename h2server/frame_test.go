@@ -513,3 +513,673 @@ func TestPingFrame_ACK(t *testing.T) {
 		}
 	}
 }
+
+func TestPingFrameBuilder_Build(t *testing.T) {
+	data := [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	got, err := NewPingFrameBuilder(data).Build()
+	if err != nil {
+		t.Fatalf("Build() got error = %v", err)
+	}
+
+	ping := got.(*PingFrame)
+	if ping.IsACK() || ping.StreamID() != 0 || ping.Data() != data || ping.Verify() != nil {
+		t.Errorf("Build() got = %+v", ping)
+	}
+}
+
+func TestPriorityFrame_Verify(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame *frame
+		want  ErrorCode
+	}{
+		{
+			name: "valid",
+			frame: &frame{
+				typ:      PriorityFrameType,
+				streamID: 3,
+				payload:  []byte{0x80, 0x00, 0x00, 0x01, 0xFF},
+			},
+			want: NoError,
+		},
+		{
+			name: "zero-stream-id",
+			frame: &frame{
+				typ:      PriorityFrameType,
+				streamID: 0,
+				payload:  []byte{0x00, 0x00, 0x00, 0x01, 0xFF},
+			},
+			want: ProtocolError,
+		},
+		{
+			name: "invalid-payload-len",
+			frame: &frame{
+				typ:      PriorityFrameType,
+				streamID: 3,
+				payload:  []byte{0x00, 0x00, 0x00, 0x01},
+			},
+			want: FrameSizeError,
+		},
+		{
+			name: "self-dependency",
+			frame: &frame{
+				typ:      PriorityFrameType,
+				streamID: 3,
+				payload:  []byte{0x80, 0x00, 0x00, 0x03, 0xFF},
+			},
+			want: ProtocolError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UnwrapErrorCode((&PriorityFrame{frame: tt.frame}).Verify())
+			if got != tt.want {
+				t.Errorf("Verify() got = %s, want = %s", got.String(), tt.want.String())
+			}
+		})
+	}
+}
+
+func TestPriorityFrame_Accessors(t *testing.T) {
+	type want struct {
+		dependency uint32
+		exclusive  bool
+		weight     uint16
+	}
+
+	tests := []struct {
+		payload []byte
+		want    want
+	}{
+		{
+			payload: []byte{0x80, 0x00, 0x00, 0x01, 0xFF},
+			want:    want{dependency: 1, exclusive: true, weight: 256},
+		},
+		{
+			payload: []byte{0x7F, 0xFF, 0xFF, 0xFF, 0x00},
+			want:    want{dependency: 0x7FFFFFFF, exclusive: false, weight: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		priority := &PriorityFrame{frame: &frame{typ: PriorityFrameType, streamID: 3, payload: tt.payload}}
+		got := want{
+			dependency: priority.StreamDependency(),
+			exclusive:  priority.IsExclusive(),
+			weight:     priority.Weight(),
+		}
+
+		if got != tt.want {
+			t.Errorf("accessors got = %+v, want = %+v", got, tt.want)
+		}
+	}
+}
+
+func TestPriorityFrameBuilder_Build(t *testing.T) {
+	type want struct {
+		frame *frame
+		err   ErrorCode
+	}
+
+	tests := []struct {
+		name    string
+		builder *PriorityFrameBuilder
+		want    want
+	}{
+		{
+			name:    "default",
+			builder: NewPriorityFrameBuilder(3),
+			want: want{
+				frame: &frame{
+					typ:      PriorityFrameType,
+					streamID: 3,
+					payload:  []byte{0x00, 0x00, 0x00, 0x00, 0x0F},
+				},
+			},
+		},
+		{
+			name:    "exclusive",
+			builder: NewPriorityFrameBuilder(3).Dependency(1, true).Weight(256),
+			want: want{
+				frame: &frame{
+					typ:      PriorityFrameType,
+					streamID: 3,
+					payload:  []byte{0x80, 0x00, 0x00, 0x01, 0xFF},
+				},
+			},
+		},
+		{
+			name:    "zero-stream-id",
+			builder: NewPriorityFrameBuilder(0),
+			want:    want{err: ProtocolError},
+		},
+		{
+			name:    "self-dependency",
+			builder: NewPriorityFrameBuilder(3).Dependency(3, false),
+			want:    want{err: ProtocolError},
+		},
+		{
+			name:    "invalid-weight",
+			builder: NewPriorityFrameBuilder(3).Weight(0),
+			want:    want{err: ProtocolError},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotErr := tt.builder.Build()
+
+			errCode := UnwrapErrorCode(gotErr)
+			if errCode != tt.want.err {
+				t.Errorf("Build() got = %s, want = %v", errCode.String(), tt.want.err.String())
+			}
+			if gotErr != nil {
+				return
+			}
+
+			w := &PriorityFrame{frame: tt.want.frame}
+			if !reflect.DeepEqual(got, w) {
+				t.Errorf("Build() got = %+v, want = %+v", got, w)
+			}
+		})
+	}
+}
+
+func TestWindowUpdateFrame_Verify(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame *frame
+		want  ErrorCode
+	}{
+		{
+			name:  "valid",
+			frame: &frame{typ: WindowUpdateFrameType, streamID: 1, payload: []byte{0x00, 0x00, 0x00, 0x01}},
+			want:  NoError,
+		},
+		{
+			name:  "invalid-payload-len",
+			frame: &frame{typ: WindowUpdateFrameType, streamID: 1, payload: []byte{0x00, 0x00, 0x01}},
+			want:  FrameSizeError,
+		},
+		{
+			name:  "zero-increment-for-stream",
+			frame: &frame{typ: WindowUpdateFrameType, streamID: 1, payload: []byte{0x00, 0x00, 0x00, 0x00}},
+			want:  ProtocolError,
+		},
+		{
+			name:  "zero-increment-for-connection",
+			frame: &frame{typ: WindowUpdateFrameType, streamID: 0, payload: []byte{0x80, 0x00, 0x00, 0x00}},
+			want:  ProtocolError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UnwrapErrorCode((&WindowUpdateFrame{frame: tt.frame}).Verify())
+			if got != tt.want {
+				t.Errorf("Verify() got = %s, want = %s", got.String(), tt.want.String())
+			}
+		})
+	}
+}
+
+func TestWindowUpdateFrame_WindowSizeIncrement(t *testing.T) {
+	tests := []struct {
+		payload []byte
+		want    uint32
+	}{
+		{payload: []byte{0x00, 0x00, 0x00, 0x01}, want: 1},
+		{payload: []byte{0xFF, 0xFF, 0xFF, 0xFF}, want: 0x7FFFFFFF},
+	}
+
+	for _, tt := range tests {
+		got := (&WindowUpdateFrame{frame: &frame{typ: WindowUpdateFrameType, payload: tt.payload}}).WindowSizeIncrement()
+		if got != tt.want {
+			t.Errorf("WindowSizeIncrement() got = %d, want = %d", got, tt.want)
+		}
+	}
+}
+
+func TestWindowUpdateFrameBuilder_Build(t *testing.T) {
+	type want struct {
+		frame *frame
+		err   ErrorCode
+	}
+
+	tests := []struct {
+		name    string
+		builder *WindowUpdateFrameBuilder
+		want    want
+	}{
+		{
+			name:    "connection",
+			builder: NewWindowUpdateFrameBuilder(0, 0x7FFFFFFF),
+			want: want{
+				frame: &frame{
+					typ:      WindowUpdateFrameType,
+					streamID: 0,
+					payload:  []byte{0x7F, 0xFF, 0xFF, 0xFF},
+				},
+			},
+		},
+		{
+			name:    "stream",
+			builder: NewWindowUpdateFrameBuilder(1, 0x1234),
+			want: want{
+				frame: &frame{
+					typ:      WindowUpdateFrameType,
+					streamID: 1,
+					payload:  []byte{0x00, 0x00, 0x12, 0x34},
+				},
+			},
+		},
+		{
+			name:    "zero-increment",
+			builder: NewWindowUpdateFrameBuilder(1, 0),
+			want:    want{err: FlowControlError},
+		},
+		{
+			name:    "too-large-increment",
+			builder: NewWindowUpdateFrameBuilder(1, 1<<31),
+			want:    want{err: FlowControlError},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotErr := tt.builder.Build()
+
+			errCode := UnwrapErrorCode(gotErr)
+			if errCode != tt.want.err {
+				t.Errorf("Build() got = %s, want = %v", errCode.String(), tt.want.err.String())
+			}
+			if gotErr != nil {
+				return
+			}
+
+			w := &WindowUpdateFrame{frame: tt.want.frame}
+			if !reflect.DeepEqual(got, w) {
+				t.Errorf("Build() got = %+v, want = %+v", got, w)
+			}
+		})
+	}
+}
+
+func TestDataFrameBuilder_Build(t *testing.T) {
+	type want struct {
+		frame *frame
+		err   ErrorCode
+	}
+
+	tests := []struct {
+		name    string
+		builder *DataFrameBuilder
+		want    want
+	}{
+		{
+			name:    "plain",
+			builder: NewDataFrameBuilder(1, []byte("Hello")),
+			want: want{
+				frame: &frame{typ: DataFrameType, flags: 0x00, streamID: 1, payload: []byte("Hello")},
+			},
+		},
+		{
+			name:    "end_stream+padded",
+			builder: NewDataFrameBuilder(1, []byte("Hello")).EndStream().Padding(3),
+			want: want{
+				frame: &frame{
+					typ:      DataFrameType,
+					flags:    0x09,
+					streamID: 1,
+					payload:  []byte{0x03, 0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x00, 0x00, 0x00},
+				},
+			},
+		},
+		{
+			name:    "zero-stream-id",
+			builder: NewDataFrameBuilder(0, []byte("Hello")),
+			want:    want{err: ProtocolError},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotErr := tt.builder.Build()
+
+			errCode := UnwrapErrorCode(gotErr)
+			if errCode != tt.want.err {
+				t.Errorf("Build() got = %s, want = %v", errCode.String(), tt.want.err.String())
+			}
+			if gotErr != nil {
+				return
+			}
+
+			w := &DataFrame{frame: tt.want.frame}
+			if !reflect.DeepEqual(got, w) {
+				t.Errorf("Build() got = %+v, want = %+v", got, w)
+			}
+
+			if err := got.(*DataFrame).Verify(); err != nil {
+				t.Errorf("Verify() of built frame got error = %v", err)
+			}
+		})
+	}
+}
+
+func TestHeadersFrameBuilder_Build(t *testing.T) {
+	type want struct {
+		frames []*frame
+		err    ErrorCode
+	}
+
+	largeBlock := bytes.Repeat([]byte{0xAB}, defaultMaxFrameSize*2+10)
+
+	tests := []struct {
+		name    string
+		builder *HeadersFrameBuilder
+		want    want
+	}{
+		{
+			name:    "single",
+			builder: NewHeadersFrameBuilder(1, []byte{0x12, 0x34}).EndStream(),
+			want: want{
+				frames: []*frame{
+					{typ: HeadersFrameType, flags: 0x05, streamID: 1, payload: []byte{0x12, 0x34}},
+				},
+			},
+		},
+		{
+			name:    "padded+prioritized",
+			builder: NewHeadersFrameBuilder(3, []byte{0x12, 0x34}).Padding(2).Priority(1, true, 256),
+			want: want{
+				frames: []*frame{
+					{
+						typ:      HeadersFrameType,
+						flags:    0x2C,
+						streamID: 3,
+						payload: []byte{
+							0x02,
+							0x80, 0x00, 0x00, 0x01,
+							0xFF,
+							0x12, 0x34,
+							0x00, 0x00,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:    "continuation",
+			builder: NewHeadersFrameBuilder(1, largeBlock).Priority(0, false, 16),
+			want: want{
+				frames: []*frame{
+					{
+						typ:      HeadersFrameType,
+						flags:    0x20,
+						streamID: 1,
+						payload:  append([]byte{0x00, 0x00, 0x00, 0x00, 0x0F}, largeBlock[:defaultMaxFrameSize-5]...),
+					},
+					{
+						typ:      ContinuationFrameType,
+						flags:    0x00,
+						streamID: 1,
+						payload:  largeBlock[defaultMaxFrameSize-5 : defaultMaxFrameSize*2-5],
+					},
+					{
+						typ:      ContinuationFrameType,
+						flags:    0x04,
+						streamID: 1,
+						payload:  largeBlock[defaultMaxFrameSize*2-5:],
+					},
+				},
+			},
+		},
+		{
+			name:    "extended_max_frame_size",
+			builder: NewHeadersFrameBuilder(1, largeBlock).MaxFrameSize(1 << 16),
+			want: want{
+				frames: []*frame{
+					{typ: HeadersFrameType, flags: 0x04, streamID: 1, payload: largeBlock},
+				},
+			},
+		},
+		{
+			name:    "invalid_max_frame_size",
+			builder: NewHeadersFrameBuilder(1, largeBlock).MaxFrameSize(100),
+			want:    want{err: FlowControlError},
+		},
+		{
+			name:    "self-dependency",
+			builder: NewHeadersFrameBuilder(1, []byte{}).Priority(1, false, 16),
+			want:    want{err: ProtocolError},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotErr := tt.builder.Build()
+
+			errCode := UnwrapErrorCode(gotErr)
+			if errCode != tt.want.err {
+				t.Errorf("Build() got = %s, want = %v", errCode.String(), tt.want.err.String())
+			}
+			if gotErr != nil {
+				return
+			}
+
+			if len(got) != len(tt.want.frames) {
+				t.Fatalf("Build() got %d frames, want = %d frames", len(got), len(tt.want.frames))
+			}
+
+			for i, f := range got {
+				w := tt.want.frames[i]
+				if f.Type() != w.typ || f.Flags() != w.flags || f.StreamID() != w.streamID || bytes.Compare(f.Payload(), w.payload) != 0 {
+					t.Errorf("Build()[%d] got = %+v, want = %+v", i, f, w)
+				}
+
+				if err := newIncomingFrame(&frame{typ: f.Type(), flags: f.Flags(), streamID: f.StreamID(), payload: f.Payload()}).Verify(); err != nil {
+					t.Errorf("Verify() of built frame[%d] got error = %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPushPromiseFrameBuilder_Build(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *PushPromiseFrameBuilder
+		want    []*frame
+	}{
+		{
+			name:    "single",
+			builder: NewPushPromiseFrameBuilder(1, 2, []byte{0x12, 0x34}).Padding(1),
+			want: []*frame{
+				{
+					typ:      PushPromiseFrameType,
+					flags:    0x0C,
+					streamID: 1,
+					payload:  []byte{0x01, 0x00, 0x00, 0x00, 0x02, 0x12, 0x34, 0x00},
+				},
+			},
+		},
+		{
+			name:    "continuation",
+			builder: NewPushPromiseFrameBuilder(1, 2, make([]byte, defaultMaxFrameSize)),
+			want: []*frame{
+				{
+					typ:      PushPromiseFrameType,
+					flags:    0x00,
+					streamID: 1,
+					payload:  append([]byte{0x00, 0x00, 0x00, 0x02}, make([]byte, defaultMaxFrameSize-4)...),
+				},
+				{
+					typ:      ContinuationFrameType,
+					flags:    0x04,
+					streamID: 1,
+					payload:  make([]byte, 4),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.builder.Build()
+			if err != nil {
+				t.Fatalf("Build() got error = %v", err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("Build() got %d frames, want = %d frames", len(got), len(tt.want))
+			}
+
+			for i, f := range got {
+				w := tt.want[i]
+				if f.Type() != w.typ || f.Flags() != w.flags || f.StreamID() != w.streamID || bytes.Compare(f.Payload(), w.payload) != 0 {
+					t.Errorf("Build()[%d] got = %+v, want = %+v", i, f, w)
+				}
+			}
+
+			push := got[0].(*PushPromiseFrame)
+			if push.PromisedStreamID() != 2 {
+				t.Errorf("PromisedStreamID() got = %d, want = 2", push.PromisedStreamID())
+			}
+		})
+	}
+}
+
+func TestRstStreamFrameBuilder_Build(t *testing.T) {
+	got, err := NewRstStreamFrameBuilder(1, CancelError).Build()
+	if err != nil {
+		t.Fatalf("Build() got error = %v", err)
+	}
+
+	want := &RstStreamFrame{frame: &frame{typ: RstStreamFrameType, streamID: 1, payload: []byte{0x00, 0x00, 0x00, 0x08}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Build() got = %+v, want = %+v", got, want)
+	}
+
+	if _, err := NewRstStreamFrameBuilder(0, CancelError).Build(); UnwrapErrorCode(err) != ProtocolError {
+		t.Errorf("Build() for stream ID(0) got error = %v", err)
+	}
+}
+
+func TestGoAwayFrameBuilder_Build(t *testing.T) {
+	got, err := NewGoAwayFrameBuilder(5, ProtocolError).DebugData([]byte("bye")).Build()
+	if err != nil {
+		t.Fatalf("Build() got error = %v", err)
+	}
+
+	want := &GoAwayFrame{
+		frame: &frame{
+			typ:      GoAwayFrameType,
+			streamID: 0,
+			payload: []byte{
+				0x00, 0x00, 0x00, 0x05,
+				0x00, 0x00, 0x00, 0x01,
+				0x62, 0x79, 0x65,
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Build() got = %+v, want = %+v", got, want)
+	}
+}
+
+func TestContinuationFrameBuilder_Build(t *testing.T) {
+	got, err := NewContinuationFrameBuilder(1, []byte{0x12}).EndHeaders().Build()
+	if err != nil {
+		t.Fatalf("Build() got error = %v", err)
+	}
+
+	want := &ContinuationFrame{frame: &frame{typ: ContinuationFrameType, flags: 0x04, streamID: 1, payload: []byte{0x12}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Build() got = %+v, want = %+v", got, want)
+	}
+}
+
+// repeatReader reads data repeatedly without allocation.
+type repeatReader struct {
+	data   []byte
+	offset int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	n := copy(p, r.data[r.offset:])
+	r.offset = (r.offset + n) % len(r.data)
+	return n, nil
+}
+
+var (
+	controlFrames = []struct {
+		name string
+		raw  []byte
+	}{
+		{
+			name: "ping",
+			raw:  []byte{0x00, 0x00, 0x08, 0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 1, 2, 3, 4, 5, 6, 7, 8},
+		},
+		{
+			name: "settings",
+			raw: []byte{
+				0x00, 0x00, 0x0C, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x03, 0x00, 0x00, 0x00, 0x64,
+				0x00, 0x04, 0x00, 0x01, 0x00, 0x00,
+			},
+		},
+		{
+			name: "window_update",
+			raw:  []byte{0x00, 0x00, 0x04, 0x08, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x10, 0x00},
+		},
+	}
+)
+
+func TestFramer_ReadFrame_PooledZeroAllocation(t *testing.T) {
+	for _, tt := range controlFrames {
+		t.Run(tt.name, func(t *testing.T) {
+			fr := NewFramer(readWriter{Reader: &repeatReader{data: tt.raw}})
+			fr.SetPooled(true)
+
+			allocs := testing.AllocsPerRun(100, func() {
+				f, err := fr.ReadFrame()
+				if err != nil {
+					t.Fatalf("ReadFrame() got error = %v", err)
+				}
+				if err := f.Verify(); err != nil {
+					t.Fatalf("Verify() got error = %v", err)
+				}
+			})
+
+			if allocs != 0 {
+				t.Errorf("ReadFrame() allocated %.1f times per frame", allocs)
+			}
+		})
+	}
+}
+
+func BenchmarkFramer_ReadFrame(b *testing.B) {
+	for _, pooled := range []bool{false, true} {
+		for _, bb := range controlFrames {
+			b.Run(fmt.Sprintf("%s/pooled=%v", bb.name, pooled), func(b *testing.B) {
+				fr := NewFramer(readWriter{Reader: &repeatReader{data: bb.raw}})
+				fr.SetPooled(pooled)
+
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					f, err := fr.ReadFrame()
+					if err != nil {
+						b.Fatal(err)
+					}
+					if err := f.Verify(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package h2server
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// Framer reads and writes frames on a connection.
+	// Payload of a frame returned by ReadFrame refers to a buffer owned by the Framer,
+	// so it's valid only until the next call of ReadFrame.
+	// Frames written by WriteFrame are buffered until Flush is called.
+	//
+	// In pooled mode, frames themselves are also reused and ReadFrame doesn't allocate in steady state.
+	// A frame is valid until the next call of ReadFrame unless it's retained by Retain.
+	// Retained frame is valid until it's passed to Release.
+	Framer struct {
+		rw                io.ReadWriter
+		header            [frameHeaderLen]byte
+		readBuf           []byte
+		writeBuf          []byte
+		maxReadFrameSize  uint32
+		maxWriteFrameSize uint32
+		registry          *FrameRegistry
+		stats             WriteStats
+		bufferedFrames    uint64
+		pooled            bool
+		last              *frameSlot
+
+		// retained is slots retained by Retain. It's guarded by retainMu because Release can be called from any goroutine.
+		retainMu sync.Mutex
+		retained map[*frameSlot]struct{}
+
+		// headerN and payloadN are octets of the frame read so far, and reading is the frame being read.
+		// They're kept when reading is interrupted by timeout, so that next ReadFrame resumes the frame.
+		headerN     int
+		payloadN    int
+		reading     *frame
+		readingSlot *frameSlot
+	}
+
+	WriteStats struct {
+		Frames  uint64
+		Octets  uint64
+		Flushes uint64
+	}
+)
+
+const (
+	frameHeaderLen = 9
+
+	// See: https://tools.ietf.org/html/rfc7540#section-6.5.2
+	defaultMaxFrameSize = 1 << 14
+)
+
+func NewFramer(rw io.ReadWriter) *Framer {
+	return &Framer{
+		rw:                rw,
+		maxReadFrameSize:  defaultMaxFrameSize,
+		maxWriteFrameSize: defaultMaxFrameSize,
+	}
+}
+
+func (fr *Framer) MaxReadFrameSize() uint32 {
+	return atomic.LoadUint32(&fr.maxReadFrameSize)
+}
+
+// SetMaxReadFrameSize sets max frame size that we advertised to peer.
+func (fr *Framer) SetMaxReadFrameSize(size uint32) error {
+	if err := (&SettingsFrameParam{ID: MaxFrameSizeSetting, Value: size}).Verify(); err != nil {
+		return err
+	}
+
+	atomic.StoreUint32(&fr.maxReadFrameSize, size)
+	return nil
+}
+
+func (fr *Framer) MaxWriteFrameSize() uint32 {
+	return atomic.LoadUint32(&fr.maxWriteFrameSize)
+}
+
+// SetMaxWriteFrameSize sets max frame size that peer advertised to us.
+func (fr *Framer) SetMaxWriteFrameSize(size uint32) error {
+	if err := (&SettingsFrameParam{ID: MaxFrameSizeSetting, Value: size}).Verify(); err != nil {
+		return err
+	}
+
+	atomic.StoreUint32(&fr.maxWriteFrameSize, size)
+	return nil
+}
+
+// SetFrameRegistry sets registry used to decode extension frames.
+func (fr *Framer) SetFrameRegistry(registry *FrameRegistry) {
+	fr.registry = registry
+}
+
+// SetPooled enables or disables pooled mode.
+func (fr *Framer) SetPooled(pooled bool) {
+	fr.releaseLast()
+	fr.pooled = pooled
+}
+
+// Retain keeps the frame returned by the last ReadFrame valid until it's passed to Release.
+// It must be called before the next ReadFrame. It returns false if the frame isn't decoded in pooled mode.
+func (fr *Framer) Retain(f Frame) bool {
+	pooled, ok := f.(pooledFrame)
+	if !ok || fr.last == nil || pooled.pooledSlot() != fr.last {
+		return false
+	}
+
+	fr.retainMu.Lock()
+	defer fr.retainMu.Unlock()
+
+	if fr.retained == nil {
+		fr.retained = make(map[*frameSlot]struct{})
+	}
+	fr.retained[fr.last] = struct{}{}
+	fr.last = nil
+	return true
+}
+
+// Release releases the retained frame. The frame must not be used after calling this.
+// It returns false and does nothing if the frame isn't retained, such as the frame released already.
+// Unlike other methods, it's safe to call from any goroutine.
+func (fr *Framer) Release(f Frame) bool {
+	pooled, ok := f.(pooledFrame)
+	if !ok {
+		return false
+	}
+
+	fr.retainMu.Lock()
+	defer fr.retainMu.Unlock()
+
+	slot := pooled.pooledSlot()
+	if _, ok := fr.retained[slot]; !ok {
+		return false
+	}
+
+	delete(fr.retained, slot)
+	slot.release()
+	return true
+}
+
+func (fr *Framer) releaseLast() {
+	if fr.last != nil {
+		fr.last.release()
+		fr.last = nil
+	}
+}
+
+// ReadFrame reads a frame.
+// If reading is interrupted before receiving any octet of the frame, or by timeout, the error is returned as it is.
+// In the latter case, the octets read so far are kept and the next call of ReadFrame resumes the frame.
+// If decoder of extension frame fails, ExtensionFrameError is returned and the frame can be ignored.
+// Otherwise, the error is wrapped because the connection can't be used any longer.
+func (fr *Framer) ReadFrame() (IncomingFrame, error) {
+	if fr.reading == nil {
+		n, err := io.ReadFull(fr.rw, fr.header[fr.headerN:])
+		fr.headerN += n
+		if err != nil {
+			return nil, fr.interrupted(err, fr.headerN, "header")
+		}
+
+		fr.headerN = 0
+		if err := fr.startFrame(); err != nil {
+			return nil, err
+		}
+	}
+
+	n, err := io.ReadFull(fr.rw, fr.reading.payload[fr.payloadN:])
+	fr.payloadN += n
+	if err != nil {
+		return nil, fr.interrupted(err, frameHeaderLen+fr.payloadN, "payload")
+	}
+
+	f, slot := fr.reading, fr.readingSlot
+	fr.reading, fr.readingSlot, fr.payloadN = nil, nil, 0
+
+	if slot != nil {
+		fr.last = slot
+		return fr.decodeExtension(slot.incoming())
+	}
+	return fr.decodeExtension(newIncomingFrame(f))
+}
+
+// startFrame decodes the frame header read, and prepares buffer to read its payload.
+func (fr *Framer) startFrame() error {
+	if !fr.pooled {
+		f := &frame{}
+		payloadLen := decodeFrameHeader(fr.header[:], f)
+		if err := fr.verifyPayloadLen(payloadLen); err != nil {
+			return err
+		}
+
+		if uint32(cap(fr.readBuf)) < payloadLen {
+			fr.readBuf = make([]byte, payloadLen)
+		}
+		f.payload = fr.readBuf[:payloadLen]
+		fr.reading = f
+		return nil
+	}
+
+	fr.releaseLast()
+
+	slot := acquireFrameSlot()
+	payloadLen := decodeFrameHeader(fr.header[:], &slot.f)
+	if err := fr.verifyPayloadLen(payloadLen); err != nil {
+		slot.release()
+		return err
+	}
+
+	slot.f.payload = slot.payloadBuffer(payloadLen)
+	fr.reading, fr.readingSlot = &slot.f, slot
+	return nil
+}
+
+// interrupted returns the error as it is if the frame can be read again by the next ReadFrame.
+func (fr *Framer) interrupted(err error, read int, part string) error {
+	if read == 0 {
+		return err
+	}
+
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return err
+	}
+
+	if fr.readingSlot != nil {
+		fr.readingSlot.release()
+	}
+	fr.headerN, fr.payloadN, fr.reading, fr.readingSlot = 0, 0, nil, nil
+	return fmt.Errorf("failed to read frame %s: %w", part, err)
+}
+
+func (fr *Framer) verifyPayloadLen(payloadLen uint32) error {
+	if maxSize := fr.MaxReadFrameSize(); payloadLen > maxSize {
+		return NewH2Error(FrameSizeError, "frame's payload length(%d octets) exceeds max frame size(%d octets)", payloadLen, maxSize)
+	}
+	return nil
+}
+
+func (fr *Framer) decodeExtension(incoming IncomingFrame) (IncomingFrame, error) {
+	if unknown, ok := incoming.(*UnknownFrame); ok && fr.registry != nil {
+		return fr.registry.decode(unknown)
+	}
+
+	return incoming, nil
+}
+
+// WriteFrame encodes a frame into write buffer. Call Flush to send buffered frames.
+func (fr *Framer) WriteFrame(f Frame) error {
+	pLen := len(f.Payload())
+	if maxSize := fr.MaxWriteFrameSize(); uint32(pLen) > maxSize {
+		return NewH2Error(FrameSizeError, "frame's payload length(%d octets) exceeds peer's max frame size(%d octets)", pLen, maxSize)
+	}
+
+	fr.writeBuf = appendFrame(fr.writeBuf, f)
+	fr.bufferedFrames++
+	return nil
+}
+
+// Buffered returns length of frames buffered but not flushed yet.
+func (fr *Framer) Buffered() int {
+	return len(fr.writeBuf)
+}
+
+// Flush sends all buffered frames with single Write call.
+func (fr *Framer) Flush() error {
+	if len(fr.writeBuf) == 0 {
+		return nil
+	}
+
+	n, err := fr.rw.Write(fr.writeBuf)
+	atomic.AddUint64(&fr.stats.Octets, uint64(n))
+	atomic.AddUint64(&fr.stats.Flushes, 1)
+
+	// Frames are counted only if they're flushed entirely.
+	if err == nil {
+		atomic.AddUint64(&fr.stats.Frames, fr.bufferedFrames)
+	}
+
+	fr.writeBuf = fr.writeBuf[:0]
+	fr.bufferedFrames = 0
+	return err
+}
+
+// WriteStats returns statistics of frames flushed so far.
+func (fr *Framer) WriteStats() WriteStats {
+	return WriteStats{
+		Frames:  atomic.LoadUint64(&fr.stats.Frames),
+		Octets:  atomic.LoadUint64(&fr.stats.Octets),
+		Flushes: atomic.LoadUint64(&fr.stats.Flushes),
+	}
+}
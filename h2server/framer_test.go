@@ -0,0 +1,360 @@
+package h2server
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+type (
+	readWriter struct {
+		io.Reader
+		io.Writer
+	}
+
+	// timeoutReader returns timeout error for each nil chunk, as read deadline fires between chunks.
+	timeoutReader struct {
+		chunks [][]byte
+	}
+
+	timeoutError struct{}
+)
+
+func (r *timeoutReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+
+	chunk := r.chunks[0]
+	if chunk == nil {
+		r.chunks = r.chunks[1:]
+		return 0, timeoutError{}
+	}
+
+	n := copy(p, chunk)
+	if r.chunks[0] = chunk[n:]; len(r.chunks[0]) == 0 {
+		r.chunks = r.chunks[1:]
+	}
+	return n, nil
+}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestFramer_ReadFrame(t *testing.T) {
+	type want struct {
+		typ      FrameType
+		streamID uint32
+		payload  []byte
+		err      ErrorCode
+	}
+
+	tests := []struct {
+		name    string
+		in      []byte
+		maxSize uint32
+		want    want
+	}{
+		{
+			name: "valid",
+			in: []byte{
+				0x00, 0x00, 0x05,
+				0x06, 0x00,
+				0x00, 0x00, 0x00, 0x00,
+				0x48, 0x65, 0x6c, 0x6c, 0x6f,
+			},
+			maxSize: defaultMaxFrameSize,
+			want: want{
+				typ:     PingFrameType,
+				payload: []byte("Hello"),
+			},
+		},
+		{
+			name: "reserved_bit",
+			in: []byte{
+				0x00, 0x00, 0x00,
+				0x00, 0x00,
+				0x80, 0x00, 0x00, 0x01,
+			},
+			maxSize: defaultMaxFrameSize,
+			want: want{
+				typ:      DataFrameType,
+				streamID: 1,
+				payload:  []byte{},
+			},
+		},
+		{
+			name: "exceed_max_frame_size",
+			in: []byte{
+				0x00, 0x40, 0x01,
+				0x00, 0x00,
+				0x00, 0x00, 0x00, 0x01,
+			},
+			maxSize: defaultMaxFrameSize,
+			want:    want{err: FrameSizeError},
+		},
+		{
+			name: "extended_max_frame_size",
+			in: append([]byte{
+				0x00, 0x40, 0x01,
+				0x00, 0x00,
+				0x00, 0x00, 0x00, 0x01,
+			}, make([]byte, 0x4001)...),
+			maxSize: 1 << 15,
+			want: want{
+				typ:      DataFrameType,
+				streamID: 1,
+				payload:  make([]byte, 0x4001),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fr := NewFramer(readWriter{Reader: iotest.OneByteReader(bytes.NewReader(tt.in))})
+			if err := fr.SetMaxReadFrameSize(tt.maxSize); err != nil {
+				t.Fatalf("SetMaxReadFrameSize() got error = %v", err)
+			}
+
+			got, err := fr.ReadFrame()
+			if errCode := UnwrapErrorCode(err); errCode != tt.want.err {
+				t.Fatalf("ReadFrame() got = %s, want = %s", errCode.String(), tt.want.err.String())
+			}
+			if err != nil {
+				return
+			}
+
+			if got.Type() != tt.want.typ ||
+				got.StreamID() != tt.want.streamID ||
+				bytes.Compare(got.Payload(), tt.want.payload) != 0 {
+				t.Errorf("ReadFrame() got = %+v, want = %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFramer_ReadFrame_Truncated(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want error
+	}{
+		{name: "empty", in: []byte{}, want: io.EOF},
+		{name: "truncated_header", in: []byte{0x00, 0x00}, want: io.ErrUnexpectedEOF},
+		{name: "truncated_payload", in: []byte{0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0xFF}, want: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFramer(readWriter{Reader: bytes.NewReader(tt.in)}).ReadFrame()
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ReadFrame() got error = %v, want = %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFramer_ReadFrame_Timeout(t *testing.T) {
+	ping := []byte{0x00, 0x00, 0x08, 0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 1, 2, 3, 4, 5, 6, 7, 8}
+
+	for _, pooled := range []bool{false, true} {
+		// PING frame is split by timeouts in its header and payload.
+		r := &timeoutReader{chunks: [][]byte{nil, ping[:4], nil, ping[4:12], nil, nil, ping[12:]}}
+		fr := NewFramer(readWriter{Reader: r})
+		fr.SetPooled(pooled)
+
+		var f IncomingFrame
+		var err error
+		timeouts := 0
+
+		for f == nil {
+			if f, err = fr.ReadFrame(); err != nil {
+				if err != (timeoutError{}) {
+					t.Fatalf("ReadFrame() got error = %v, want = timeout", err)
+				}
+				timeouts++
+			}
+		}
+
+		if timeouts != 4 {
+			t.Errorf("ReadFrame() got %d timeouts, want = 4", timeouts)
+		}
+
+		got, ok := f.(*PingFrame)
+		if !ok || got.Data() != [8]byte{1, 2, 3, 4, 5, 6, 7, 8} {
+			t.Errorf("ReadFrame() got = %+v, want = PING frame", f)
+		}
+	}
+}
+
+func TestFramer_WriteFrame(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame Frame
+		want  []byte
+		err   ErrorCode
+	}{
+		{
+			name:  "valid",
+			frame: &PingFrame{frame: &frame{typ: PingFrameType, flags: 0x01, payload: []byte{1, 2, 3, 4, 5, 6, 7, 8}}},
+			want: []byte{
+				0x00, 0x00, 0x08,
+				0x06, 0x01,
+				0x00, 0x00, 0x00, 0x00,
+				1, 2, 3, 4, 5, 6, 7, 8,
+			},
+		},
+		{
+			name:  "exceed_max_frame_size",
+			frame: &DataFrame{frame: &frame{typ: DataFrameType, streamID: 1, payload: make([]byte, defaultMaxFrameSize+1)}},
+			err:   FrameSizeError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrote := bytes.NewBuffer(nil)
+			fr := NewFramer(readWriter{Writer: wrote})
+
+			err := fr.WriteFrame(tt.frame)
+			if err == nil {
+				err = fr.Flush()
+			}
+
+			if errCode := UnwrapErrorCode(err); errCode != tt.err {
+				t.Fatalf("WriteFrame() got = %s, want = %s", errCode.String(), tt.err.String())
+			}
+			if err != nil {
+				return
+			}
+
+			if bytes.Compare(wrote.Bytes(), tt.want) != 0 {
+				t.Errorf("WriteFrame() got = %v, want = %v", wrote.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+// countingWriter counts writes. If err isn't nil, Write fails with it.
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+	err    error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	cw.writes++
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	return cw.Buffer.Write(p)
+}
+
+func TestFramer_Flush(t *testing.T) {
+	w := &countingWriter{}
+	fr := NewFramer(readWriter{Writer: w})
+
+	ping := &PingFrame{frame: &frame{typ: PingFrameType, payload: make([]byte, 8)}}
+	for i := 0; i < 3; i++ {
+		if err := fr.WriteFrame(ping); err != nil {
+			t.Fatalf("WriteFrame() got error = %v", err)
+		}
+	}
+
+	if w.writes != 0 || fr.Buffered() != 3*17 {
+		t.Fatalf("WriteFrame() wrote before Flush(): writes = %d, buffered = %d", w.writes, fr.Buffered())
+	}
+
+	if got := fr.WriteStats(); got != (WriteStats{}) {
+		t.Errorf("WriteStats() before Flush() got = %+v, want zero", got)
+	}
+
+	if err := fr.Flush(); err != nil {
+		t.Fatalf("Flush() got error = %v", err)
+	}
+
+	if w.writes != 1 || w.Len() != 3*17 || fr.Buffered() != 0 {
+		t.Errorf("Flush() got writes = %d, octets = %d, buffered = %d", w.writes, w.Len(), fr.Buffered())
+	}
+
+	if err := fr.Flush(); err != nil || w.writes != 1 {
+		t.Errorf("Flush() with empty buffer got writes = %d, error = %v", w.writes, err)
+	}
+
+	want := WriteStats{Frames: 3, Octets: 3 * 17, Flushes: 1}
+	if got := fr.WriteStats(); got != want {
+		t.Errorf("WriteStats() got = %+v, want = %+v", got, want)
+	}
+}
+
+func TestFramer_Flush_Error(t *testing.T) {
+	w := &countingWriter{err: errors.New("broken connection")}
+	fr := NewFramer(readWriter{Writer: w})
+
+	ping := &PingFrame{frame: &frame{typ: PingFrameType, payload: make([]byte, 8)}}
+	if err := fr.WriteFrame(ping); err != nil {
+		t.Fatalf("WriteFrame() got error = %v", err)
+	}
+
+	if err := fr.Flush(); err != w.err {
+		t.Fatalf("Flush() got error = %v, want = %v", err, w.err)
+	}
+
+	// Frames failed to flush aren't counted.
+	want := WriteStats{Flushes: 1}
+	if got := fr.WriteStats(); got != want {
+		t.Errorf("WriteStats() got = %+v, want = %+v", got, want)
+	}
+}
+
+func TestFramer_Retain(t *testing.T) {
+	in := []byte{
+		0x00, 0x00, 0x08, 0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 1, 1, 1, 1, 1, 1, 1, 1,
+		0x00, 0x00, 0x08, 0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 2, 2, 2, 2, 2, 2, 2, 2,
+		0x00, 0x00, 0x08, 0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 3, 3, 3, 3, 3, 3, 3, 3,
+	}
+
+	fr := NewFramer(readWriter{Reader: bytes.NewReader(in)})
+	fr.SetPooled(true)
+
+	first, err := fr.ReadFrame()
+	if err != nil {
+		t.Fatalf("ReadFrame() got error = %v", err)
+	}
+
+	if !fr.Retain(first) {
+		t.Fatalf("Retain() got false")
+	}
+
+	second, err := fr.ReadFrame()
+	if err != nil {
+		t.Fatalf("ReadFrame() got error = %v", err)
+	}
+
+	if fr.Retain(first) {
+		t.Errorf("Retain() for frame not returned by the last ReadFrame got true")
+	}
+
+	if first == second || first.Payload()[0] != 1 || second.Payload()[0] != 2 {
+		t.Errorf("retained frame is overwritten: first = %v, second = %v", first.Payload(), second.Payload())
+	}
+
+	if fr.Release(second) {
+		t.Errorf("Release() for frame not retained got true")
+	}
+
+	if !fr.Release(first) {
+		t.Errorf("Release() for retained frame got false")
+	}
+
+	if fr.Release(first) {
+		t.Errorf("Release() for frame released already got true")
+	}
+
+	if fr.Retain(&PingFrame{frame: &frame{typ: PingFrameType}}) {
+		t.Errorf("Retain() for frame not decoded in pooled mode got true")
+	}
+}
@@ -0,0 +1,129 @@
+package h2server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+
+	"github.com/murakmii/exp-h2server/h2server/hpack"
+)
+
+type (
+	// Handler responds to HTTP request received on a stream.
+	// HttpMultiplexer calls it on a goroutine for each stream.
+	Handler interface {
+		ServeHTTP2(w ResponseWriter, r *Request)
+	}
+
+	HandlerFunc func(w ResponseWriter, r *Request)
+
+	// Request is HTTP request received on a stream.
+	Request struct {
+		StreamID uint32
+
+		// Method, Scheme, Authority and Path are values of pseudo-header fields.
+		// See: https://tools.ietf.org/html/rfc7540#section-8.1.2.3
+		Method    string
+		Scheme    string
+		Authority string
+		Path      string
+
+		// Header is header fields other than pseudo-header fields.
+		Header hpack.HeaderList
+
+		// Body reads DATA frames of the request. It returns io.EOF when peer ends the stream.
+		// Flow control window is credited to peer as the body is read.
+		// If the body isn't read until EOF, the stream is reset after the response.
+		Body io.ReadCloser
+
+		// RemoteAddr is address of peer, and TLS is state of TLS if the connection is TLS.
+		RemoteAddr string
+		TLS        *tls.ConnectionState
+
+		ctx  context.Context
+		body *requestBody
+
+		// noBody is true if the request ends by HEADERS frame.
+		noBody bool
+	}
+
+	// ResponseWriter sends HTTP response on a stream.
+	// Response is completed when Handler returns.
+	ResponseWriter interface {
+		// AddHeader adds header field sent by WriteHeader. Name is converted to lower case.
+		AddHeader(name, value string)
+
+		// WriteHeader sends response header with the status. Header fields added after it are ignored.
+		WriteHeader(status int) error
+
+		// Write sends data as response body. If WriteHeader isn't called yet, it's called with 200.
+		Write(p []byte) (int, error)
+
+		// Flush waits until response written so far is flushed to the connection.
+		Flush() error
+
+		// DeclareTrailer declares name of trailer field by "trailer" header field. It must be called before WriteHeader.
+		// See: https://tools.ietf.org/html/rfc7230#section-4.4
+		DeclareTrailer(name string)
+
+		// AddTrailer adds trailer field sent by HEADERS frame after response body. Pseudo-header fields are ignored.
+		// See: https://tools.ietf.org/html/rfc7540#section-8.1
+		AddTrailer(name, value string)
+
+		// Push promises request with the method and path to peer, and calls Handler for it.
+		// Scheme and authority of the request are same as this request.
+		// It returns ErrPushNotAllowed if peer disables server push.
+		// See: https://tools.ietf.org/html/rfc7540#section-8.2
+		Push(method, path string, header hpack.HeaderList) error
+	}
+)
+
+var (
+	_ Handler = HandlerFunc(nil)
+
+	ErrPushNotAllowed = errors.New("h2server: server push isn't allowed")
+)
+
+func (f HandlerFunc) ServeHTTP2(w ResponseWriter, r *Request) {
+	f(w, r)
+}
+
+// NotFoundHandler returns handler responding 404 to all requests.
+func NotFoundHandler() Handler {
+	return HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.WriteHeader(404)
+	})
+}
+
+// Context returns context of the request. It's done when the stream is closed or the handler returns.
+func (r *Request) Context() context.Context {
+	return r.ctx
+}
+
+// Trailer returns trailer fields of the request. It returns nil until Body returns io.EOF.
+func (r *Request) Trailer() hpack.HeaderList {
+	return r.body.trailers()
+}
+
+// newRequest builds request from header list of the stream.
+func newRequest(ctx context.Context, streamID uint32, headerList hpack.HeaderList, body *requestBody) *Request {
+	req := &Request{StreamID: streamID, Body: body, ctx: ctx, body: body}
+
+	for _, hf := range headerList {
+		switch hf.Name() {
+		case ":method":
+			req.Method = hf.Value()
+		case ":scheme":
+			req.Scheme = hf.Value()
+		case ":authority":
+			req.Authority = hf.Value()
+		case ":path":
+			req.Path = hf.Value()
+		default:
+			req.Header = append(req.Header, hf)
+		}
+	}
+
+	return req
+}
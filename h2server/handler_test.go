@@ -0,0 +1,452 @@
+package h2server
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/murakmii/exp-h2server/h2server/hpack"
+)
+
+type (
+	// writtenFrame is summary of frame written by HttpMultiplexer.
+	writtenFrame struct {
+		typ    FrameType
+		eos    bool
+		header hpack.HeaderList
+		data   string
+		code   ErrorCode
+	}
+)
+
+func requestHeaders(t *testing.T, id uint32, headerList hpack.HeaderList, endStream bool) IncomingFrame {
+	t.Helper()
+
+	builder := NewHeadersFrameBuilder(id, headerList.Encode())
+	if endStream {
+		builder.EndStream()
+	}
+
+	frames, err := builder.Build()
+	return incoming(t, frames[0], err)
+}
+
+func summarize(t *testing.T, frames []Frame) []writtenFrame {
+	t.Helper()
+
+	var summary []writtenFrame
+	for _, f := range frames {
+		switch in := incoming(t, f, nil).(type) {
+		case *HeadersFrame:
+			hl, err := hpack.DecodeHeaderBlock(hpack.NewIndexTable(4096), bytes.NewReader(in.Fragment()))
+			if err != nil {
+				t.Fatalf("failed to decode header block: %v", err)
+			}
+			summary = append(summary, writtenFrame{typ: HeadersFrameType, eos: in.IsEOS(), header: hl})
+
+		case *DataFrame:
+			summary = append(summary, writtenFrame{typ: DataFrameType, eos: in.IsEOS(), data: string(in.Data())})
+
+		case *RstStreamFrame:
+			summary = append(summary, writtenFrame{typ: RstStreamFrameType, code: in.ErrorCode()})
+		}
+	}
+	return summary
+}
+
+// waitStreamsClosed waits until all streams are closed by handlers.
+func waitStreamsClosed(t *testing.T, hmp *HttpMultiplexer) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for hmp.ActiveStreams() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("streams aren't closed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHttpMultiplexer_Handler(t *testing.T) {
+	get := hpack.HeaderList{
+		hpack.NewHeaderField(":method", "GET"),
+		hpack.NewHeaderField(":scheme", "https"),
+		hpack.NewHeaderField(":authority", "example.com"),
+		hpack.NewHeaderField(":path", "/"),
+	}
+
+	post := hpack.HeaderList{
+		hpack.NewHeaderField(":method", "POST"),
+		hpack.NewHeaderField(":scheme", "https"),
+		hpack.NewHeaderField(":authority", "example.com"),
+		hpack.NewHeaderField(":path", "/echo"),
+		hpack.NewHeaderField("content-type", "text/plain"),
+	}
+
+	tests := []struct {
+		name    string
+		header  hpack.HeaderList
+		body    string
+		handler HandlerFunc
+		want    []writtenFrame
+	}{
+		{
+			name:    "default response",
+			header:  get,
+			handler: func(w ResponseWriter, r *Request) {},
+			want: []writtenFrame{
+				{typ: HeadersFrameType, eos: true, header: hpack.HeaderList{hpack.NewHeaderField(":status", "200")}},
+			},
+		},
+		{
+			name:   "status only",
+			header: get,
+			handler: func(w ResponseWriter, r *Request) {
+				w.AddHeader("Cache-Control", "no-cache")
+				w.WriteHeader(204)
+			},
+			want: []writtenFrame{
+				{typ: HeadersFrameType, header: hpack.HeaderList{
+					hpack.NewHeaderField(":status", "204"),
+					hpack.NewHeaderField("cache-control", "no-cache"),
+				}},
+				{typ: DataFrameType, eos: true},
+			},
+		},
+		{
+			name:   "echo body with trailers",
+			header: post,
+			body:   "hello",
+			handler: func(w ResponseWriter, r *Request) {
+				body, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					t.Errorf("Body.Read() got error = %v", err)
+				}
+
+				w.DeclareTrailer("X-Checksum")
+				w.Write([]byte(r.Method + " " + r.Path + " " + string(body)))
+				w.AddTrailer("X-Checksum", "1234")
+				w.AddTrailer(":status", "500")
+			},
+			want: []writtenFrame{
+				{typ: HeadersFrameType, header: hpack.HeaderList{
+					hpack.NewHeaderField(":status", "200"),
+					hpack.NewHeaderField("trailer", "x-checksum"),
+				}},
+				{typ: DataFrameType, data: "POST /echo hello"},
+				{typ: HeadersFrameType, eos: true, header: hpack.HeaderList{hpack.NewHeaderField("x-checksum", "1234")}},
+			},
+		},
+		{
+			name:   "panic",
+			header: get,
+			handler: func(w ResponseWriter, r *Request) {
+				panic("oops")
+			},
+			want: []writtenFrame{
+				{typ: RstStreamFrameType, code: InternalError},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hmp, conn := newTestMultiplexer()
+			hmp.handler = tt.handler
+
+			if err := hmp.Received(requestHeaders(t, 1, tt.header, tt.body == "")); err != nil {
+				t.Fatalf("Received() got error = %v", err)
+			}
+
+			if tt.body != "" {
+				f, err := NewDataFrameBuilder(1, []byte(tt.body)).EndStream().Build()
+				if err := hmp.Received(incoming(t, f, err)); err != nil {
+					t.Fatalf("Received() got error = %v", err)
+				}
+			}
+
+			waitStreamsClosed(t, hmp)
+
+			var frames []Frame
+			for _, f := range conn.frames() {
+				if f.Type() != WindowUpdateFrameType {
+					frames = append(frames, f)
+				}
+			}
+
+			if got := summarize(t, frames); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("written frames = %+v, want = %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHttpMultiplexer_Handler_Request(t *testing.T) {
+	hmp, _ := newTestMultiplexer()
+
+	got := make(chan *Request, 1)
+	hmp.handler = HandlerFunc(func(w ResponseWriter, r *Request) {
+		got <- r
+	})
+
+	header := hpack.HeaderList{
+		hpack.NewHeaderField(":method", "GET"),
+		hpack.NewHeaderField(":scheme", "https"),
+		hpack.NewHeaderField(":authority", "example.com"),
+		hpack.NewHeaderField(":path", "/index.html"),
+		hpack.NewHeaderField("accept", "text/html"),
+	}
+
+	if err := hmp.Received(requestHeaders(t, 3, header, true)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+
+	r := <-got
+	if r.StreamID != 3 || r.Method != "GET" || r.Scheme != "https" || r.Authority != "example.com" || r.Path != "/index.html" {
+		t.Errorf("Request = %+v", r)
+	}
+
+	if want := (hpack.HeaderList{hpack.NewHeaderField("accept", "text/html")}); !reflect.DeepEqual(r.Header, want) {
+		t.Errorf("Request.Header = %v, want = %v", r.Header, want)
+	}
+
+	waitStreamsClosed(t, hmp)
+	select {
+	case <-r.Context().Done():
+	default:
+		t.Error("Request.Context() isn't done after handler returns")
+	}
+}
+
+func TestHttpMultiplexer_Handler_Reset(t *testing.T) {
+	hmp, _ := newTestMultiplexer()
+
+	readErr := make(chan error, 1)
+	hmp.handler = HandlerFunc(func(w ResponseWriter, r *Request) {
+		_, err := ioutil.ReadAll(r.Body)
+		readErr <- err
+	})
+
+	header := hpack.HeaderList{
+		hpack.NewHeaderField(":method", "POST"),
+		hpack.NewHeaderField(":scheme", "https"),
+		hpack.NewHeaderField(":path", "/"),
+	}
+
+	if err := hmp.Received(requestHeaders(t, 1, header, false)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+
+	rst, err := NewRstStreamFrameBuilder(1, CancelError).Build()
+	if err := hmp.Received(incoming(t, rst, err)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+
+	var streamErr *StreamError
+	if err := <-readErr; !errors.As(err, &streamErr) || streamErr.Code() != CancelError {
+		t.Errorf("Body.Read() got error = %v, want = StreamError(CANCEL)", err)
+	}
+}
+
+func TestHttpMultiplexer_Handler_BlockedWrite(t *testing.T) {
+	hmp, conn := newTestMultiplexer()
+	conn.blocked = make(chan struct{})
+	hmp.handler = HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Write([]byte("hello"))
+	})
+
+	get := hpack.HeaderList{
+		hpack.NewHeaderField(":method", "GET"),
+		hpack.NewHeaderField(":scheme", "https"),
+		hpack.NewHeaderField(":path", "/"),
+	}
+
+	if err := hmp.Received(requestHeaders(t, 1, get, true)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+
+	// Wait for the handler to block in writing the response.
+	deadline := time.Now().Add(time.Second)
+	for {
+		hmp.mu.Lock()
+		writing := hmp.writing
+		hmp.mu.Unlock()
+
+		if writing > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("handler doesn't write the response")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Frames are received while the handler is blocked by the write queue.
+	f := requestHeaders(t, 3, get, true)
+	received := make(chan error, 1)
+	go func() {
+		received <- hmp.Received(f)
+	}()
+
+	select {
+	case err := <-received:
+		if err != nil {
+			t.Fatalf("Received() got error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Received() is blocked by the handler writing the response")
+	}
+
+	close(conn.blocked)
+	waitStreamsClosed(t, hmp)
+
+	var body string
+	for _, f := range summarize(t, conn.frames()) {
+		body += f.data
+	}
+
+	if body != "hellohello" {
+		t.Errorf("written body = %s, want responses of both streams", body)
+	}
+}
+
+func TestHttpMultiplexer_Handler_UnreadBody(t *testing.T) {
+	hmp, conn := newTestMultiplexer()
+	hmp.handler = HandlerFunc(func(w ResponseWriter, r *Request) {
+		r.Body.Close()
+		w.Write([]byte("ignored body"))
+	})
+
+	header := hpack.HeaderList{
+		hpack.NewHeaderField(":method", "POST"),
+		hpack.NewHeaderField(":scheme", "https"),
+		hpack.NewHeaderField(":path", "/"),
+	}
+
+	if err := hmp.Received(requestHeaders(t, 1, header, false)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+
+	f, err := NewDataFrameBuilder(1, []byte("unread")).Build()
+	if err := hmp.Received(incoming(t, f, err)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+
+	waitStreamsClosed(t, hmp)
+
+	// Peer is asked to stop sending the request after the complete response.
+	want := []writtenFrame{
+		{typ: HeadersFrameType, header: hpack.HeaderList{hpack.NewHeaderField(":status", "200")}},
+		{typ: DataFrameType, data: "ignored body"},
+		{typ: DataFrameType, eos: true},
+		{typ: RstStreamFrameType, code: NoError},
+	}
+
+	if got := summarize(t, conn.frames()); !reflect.DeepEqual(got, want) {
+		t.Errorf("written frames = %+v, want = %+v", got, want)
+	}
+}
+
+func TestHttpMultiplexer_Handler_RequestTrailer(t *testing.T) {
+	header := hpack.HeaderList{
+		hpack.NewHeaderField(":method", "POST"),
+		hpack.NewHeaderField(":scheme", "https"),
+		hpack.NewHeaderField(":path", "/"),
+		hpack.NewHeaderField("trailer", "x-checksum"),
+	}
+
+	tests := []struct {
+		name            string
+		trailer         hpack.HeaderList
+		endStream       bool
+		halfClosedLocal bool
+		want            ErrorCode
+	}{
+		{
+			name:      "trailers",
+			trailer:   hpack.HeaderList{hpack.NewHeaderField("x-checksum", "1234")},
+			endStream: true,
+		},
+		{
+			name:    "trailers without END_STREAM",
+			trailer: hpack.HeaderList{hpack.NewHeaderField("x-checksum", "1234")},
+			want:    ProtocolError,
+		},
+		{
+			name:            "trailers without END_STREAM on half-closed (local) stream",
+			trailer:         hpack.HeaderList{hpack.NewHeaderField("x-checksum", "1234")},
+			halfClosedLocal: true,
+			want:            ProtocolError,
+		},
+		{
+			name:      "pseudo-header in trailers",
+			trailer:   hpack.HeaderList{hpack.NewHeaderField(":path", "/")},
+			endStream: true,
+			want:      ProtocolError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hmp, _ := newTestMultiplexer()
+
+			type result struct {
+				body    string
+				trailer hpack.HeaderList
+				err     error
+			}
+			got := make(chan result, 1)
+
+			hmp.handler = HandlerFunc(func(w ResponseWriter, r *Request) {
+				if r.Trailer() != nil {
+					t.Error("Trailer() got trailers before EOF")
+				}
+
+				body, err := ioutil.ReadAll(r.Body)
+				got <- result{body: string(body), trailer: r.Trailer(), err: err}
+			})
+
+			if err := hmp.Received(requestHeaders(t, 1, header, false)); err != nil {
+				t.Fatalf("Received() got error = %v", err)
+			}
+
+			f, err := NewDataFrameBuilder(1, []byte("body")).Build()
+			if err := hmp.Received(incoming(t, f, err)); err != nil {
+				t.Fatalf("Received() got error = %v", err)
+			}
+
+			if tt.halfClosedLocal {
+				hmp.mu.Lock()
+				hmp.streams[1].state = StreamHalfClosedLocal
+				hmp.mu.Unlock()
+			}
+
+			err = hmp.Received(requestHeaders(t, 1, tt.trailer, tt.endStream))
+			if tt.want != NoError {
+				var streamErr *StreamError
+				if !errors.As(err, &streamErr) || streamErr.Code() != tt.want {
+					t.Fatalf("Received() got error = %v, want stream error %s", err, tt.want)
+				}
+
+				// Server resets the stream by the stream error.
+				hmp.Reset(1, tt.want)
+				if r := <-got; r.err == nil {
+					t.Errorf("Body.Read() got no error for invalid trailers")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Received() got error = %v", err)
+			}
+
+			r := <-got
+			if r.err != nil || r.body != "body" || !reflect.DeepEqual(r.trailer, tt.trailer) {
+				t.Errorf("handler got = %+v, want body and trailers %v", r, tt.trailer)
+			}
+		})
+	}
+}
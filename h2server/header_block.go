@@ -0,0 +1,84 @@
+package h2server
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/murakmii/exp-h2server/h2server/hpack"
+)
+
+type (
+	// headerBlock assembles header block from HEADERS or PUSH_PROMISE frame and following CONTINUATION frames.
+	// While a header block is assembled, frames other than CONTINUATION on the same stream must not be received.
+	// See: https://tools.ietf.org/html/rfc7540#section-4.3
+	headerBlock struct {
+		streamID   uint32
+		fragments  []byte
+		assembling bool
+	}
+)
+
+const (
+	// maxHeaderBlockSize limits size of header block, when our SETTINGS_MAX_HEADER_LIST_SIZE is larger or unlimited.
+	// Without the limit, peer can make the server buffer header block endlessly by CONTINUATION frames.
+	maxHeaderBlockSize = 1 << 20
+)
+
+// verify returns connection error if the frame violates sequence of header block.
+func (hb *headerBlock) verify(f Frame) error {
+	if hb.assembling {
+		if f.Type() != ContinuationFrameType || f.StreamID() != hb.streamID {
+			return NewConnectionError(ProtocolError, "header block of stream(%d) is interleaved by frame(0x%X) on stream(%d)", hb.streamID, f.Type(), f.StreamID())
+		}
+		return nil
+	}
+
+	if f.Type() == ContinuationFrameType {
+		return NewConnectionError(ProtocolError, "received continuation frame on stream(%d) without header block", f.StreamID())
+	}
+	return nil
+}
+
+// start starts assembling header block with the first fragment. It returns true if the block is completed.
+// Fragment is copied because it may refer to read buffer of Framer.
+func (hb *headerBlock) start(streamID uint32, fragment []byte, endHeaders bool, maxSize uint32) (bool, error) {
+	hb.streamID = streamID
+	hb.fragments = hb.fragments[:0]
+	return hb.append(fragment, endHeaders, maxSize)
+}
+
+// append appends the fragment of CONTINUATION frame. It returns true if the block is completed.
+// It returns connection error if the block exceeds maxSize, because the block can't be decoded any longer.
+func (hb *headerBlock) append(fragment []byte, endHeaders bool, maxSize uint32) (bool, error) {
+	if maxSize > maxHeaderBlockSize {
+		maxSize = maxHeaderBlockSize
+	}
+
+	if uint64(len(hb.fragments))+uint64(len(fragment)) > uint64(maxSize) {
+		hb.fragments = hb.fragments[:0]
+		return false, NewConnectionError(EnhanceYourCalmError, "header block of stream(%d) exceeds %d octets", hb.streamID, maxSize)
+	}
+
+	hb.fragments = append(hb.fragments, fragment...)
+	hb.assembling = !endHeaders
+	return endHeaders, nil
+}
+
+// decode decodes the completed header block with the index table.
+// Error of decoding is COMPRESSION_ERROR because the index table can't be synchronized any longer.
+// But invalid header field is detected after the whole block is decoded, so it's malformed request of the stream.
+// See: https://tools.ietf.org/html/rfc7540#section-4.3
+// See: https://tools.ietf.org/html/rfc7540#section-8.1.2.6
+func (hb *headerBlock) decode(table *hpack.IndexTable) (hpack.HeaderList, error) {
+	headerList, err := hpack.DecodeHeaderBlock(table, bytes.NewReader(hb.fragments))
+	hb.fragments = hb.fragments[:0]
+
+	if errors.Is(err, hpack.ErrHeader) {
+		return nil, NewStreamError(hb.streamID, ProtocolError, "header block of stream(%d) is malformed: %w", hb.streamID, err)
+	}
+
+	if err != nil {
+		return nil, NewConnectionError(CompressionError, "failed to decode header block of stream(%d): %w", hb.streamID, err)
+	}
+	return headerList, nil
+}
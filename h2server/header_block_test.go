@@ -0,0 +1,113 @@
+package h2server
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/murakmii/exp-h2server/h2server/hpack"
+)
+
+func TestHeaderBlock_Verify(t *testing.T) {
+	tests := []struct {
+		name       string
+		assembling bool
+		frame      Frame
+		want       ErrorCode
+	}{
+		{name: "headers", frame: &HeadersFrame{frame: &frame{typ: HeadersFrameType, streamID: 1}}, want: NoError},
+		{name: "continuation without header block", frame: &ContinuationFrame{frame: &frame{typ: ContinuationFrameType, streamID: 1}}, want: ProtocolError},
+		{name: "continuation", assembling: true, frame: &ContinuationFrame{frame: &frame{typ: ContinuationFrameType, streamID: 1}}, want: NoError},
+		{name: "continuation on other stream", assembling: true, frame: &ContinuationFrame{frame: &frame{typ: ContinuationFrameType, streamID: 3}}, want: ProtocolError},
+		{name: "interleaved by data", assembling: true, frame: &DataFrame{frame: &frame{typ: DataFrameType, streamID: 1}}, want: ProtocolError},
+		{name: "interleaved by unknown frame", assembling: true, frame: &UnknownFrame{frame: &frame{typ: 0xff, streamID: 0}}, want: ProtocolError},
+	}
+
+	for _, tt := range tests {
+		hb := &headerBlock{streamID: 1, assembling: tt.assembling}
+
+		err := hb.verify(tt.frame)
+		var connErr *ConnectionError
+		if UnwrapErrorCode(err) != tt.want || (err != nil && !errors.As(err, &connErr)) {
+			t.Errorf("%s: verify() got = %v, want = %s", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestHeaderBlock_Decode(t *testing.T) {
+	table := hpack.NewIndexTable(4096)
+	hb := &headerBlock{}
+
+	// :method: GET, :scheme: https, :path: /
+	if completed, err := hb.start(1, []byte{0x82, 0x87}, false, math.MaxUint32); completed || err != nil {
+		t.Fatalf("start() got = (%v, %v), want false without END_HEADERS", completed, err)
+	}
+
+	if completed, err := hb.append([]byte{0x84}, true, math.MaxUint32); !completed || err != nil {
+		t.Fatalf("append() got = (%v, %v), want true with END_HEADERS", completed, err)
+	}
+
+	got, err := hb.decode(table)
+	if err != nil {
+		t.Fatalf("decode() got error = %v", err)
+	}
+
+	want := []string{":method", ":scheme", ":path"}
+	if len(got) != len(want) {
+		t.Fatalf("decode() got %d fields, want = %d", len(got), len(want))
+	}
+	for i, name := range want {
+		if got[i].Name() != name {
+			t.Errorf("decode()[%d] got = %s, want = %s", i, got[i].Name(), name)
+		}
+	}
+
+	// Index 63 doesn't exist in the table.
+	hb.start(3, []byte{0xbf}, true, math.MaxUint32)
+	if _, err := hb.decode(table); UnwrapErrorCode(err) != CompressionError {
+		t.Errorf("decode() got = %v, want = %s", err, CompressionError)
+	}
+
+	// Header name must be lower case. (Literal Header Field without Indexing, A: a)
+	hb.start(5, []byte{0x00, 0x01, 'A', 0x01, 'a'}, true, math.MaxUint32)
+
+	var streamErr *StreamError
+	if _, err := hb.decode(table); !errors.As(err, &streamErr) || streamErr.StreamID() != 5 || streamErr.Code() != ProtocolError {
+		t.Errorf("decode() got = %v, want = stream error of %s", err, ProtocolError)
+	}
+}
+
+func TestHeaderBlock_MaxSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxSize   uint32
+		fragments int
+		valid     bool
+	}{
+		{name: "within max size", maxSize: 30, fragments: 3, valid: true},
+		{name: "exceeds max size", maxSize: 25, fragments: 3},
+		{name: "exceeds default limit", maxSize: math.MaxUint32, fragments: maxHeaderBlockSize/10 + 1},
+	}
+
+	for _, tt := range tests {
+		hb := &headerBlock{}
+		fragment := make([]byte, 10)
+
+		_, err := hb.start(1, fragment, false, tt.maxSize)
+		for i := 1; i < tt.fragments && err == nil; i++ {
+			_, err = hb.append(fragment, false, tt.maxSize)
+		}
+
+		if tt.valid {
+			if err != nil {
+				t.Errorf("%s: append() got error = %v", tt.name, err)
+			}
+			continue
+		}
+
+		var connErr *ConnectionError
+		if !errors.As(err, &connErr) || connErr.Code() != EnhanceYourCalmError {
+			t.Errorf("%s: append() got error = %v, want connection error %s", tt.name, err, EnhanceYourCalmError)
+		}
+	}
+}
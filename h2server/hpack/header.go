@@ -30,9 +30,13 @@ func (hl HeaderList) Validate() error {
 	return nil
 }
 
+// Encode encodes header list to header block by literal header fields never indexed.
+// Header block doesn't depend on index table, so it's decoded regardless of state of peer's table.
+// See: https://tools.ietf.org/html/rfc7541#section-6.2.3
 func (hl HeaderList) Encode() []byte {
-	buf := bytes.NewBuffer([]byte{0x10})
+	buf := bytes.NewBuffer(nil)
 	for _, hf := range hl {
+		buf.WriteByte(0x10)
 		buf.Write(encodeStringLiteral(hf.Name(), true))
 		buf.Write(encodeStringLiteral(hf.Value(), true))
 	}
@@ -136,6 +136,11 @@ func (pk *peekReader) Read(buf []byte) (int, error) {
 	if pk.peek != nil {
 		buf[0] = *pk.peek
 		offset = 1
+
+		if len(buf) == 1 {
+			pk.peek = nil
+			return offset, nil
+		}
 	}
 
 	read, err := pk.r.Read(buf[offset:])
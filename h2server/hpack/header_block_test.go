@@ -3,6 +3,7 @@ package hpack
 import (
 	"bytes"
 	"errors"
+	"io"
 	"reflect"
 	"testing"
 )
@@ -167,3 +168,19 @@ func TestPeekReader_PeekAndRead(t *testing.T) {
 		t.Errorf("Read() read = %X, want = %X", buf, in[4:])
 	}
 }
+
+func TestPeekReader_ReadPeekedLastByte(t *testing.T) {
+	r := newPeekReader(bytes.NewReader([]byte{0x01}))
+	if _, err := r.Peek(); err != nil {
+		t.Fatalf("Peek() got error = %v", err)
+	}
+
+	buf := make([]byte, 1)
+	if read, err := r.Read(buf); read != 1 || err != nil || buf[0] != 0x01 {
+		t.Errorf("Read() got = %d/%v, want = 1/nil", read, err)
+	}
+
+	if _, err := r.Peek(); err != io.EOF {
+		t.Errorf("Peek() got error = %v, want = %v", err, io.EOF)
+	}
+}
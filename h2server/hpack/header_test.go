@@ -1,6 +1,7 @@
 package hpack
 
 import (
+	"bytes"
 	"errors"
 	"testing"
 )
@@ -46,3 +47,31 @@ func TestHeaderField_Validate(t *testing.T) {
 		}
 	}
 }
+
+func TestHeaderList_Encode(t *testing.T) {
+	hl := HeaderList{
+		NewHeaderField(":status", "200"),
+		NewHeaderField("content-type", "text/plain"),
+		NewHeaderField("x-empty", ""),
+	}
+
+	table := NewIndexTable(4096)
+	got, err := DecodeHeaderBlock(table, bytes.NewReader(hl.Encode()))
+	if err != nil {
+		t.Fatalf("DecodeHeaderBlock() got error = %v", err)
+	}
+
+	if len(got) != len(hl) {
+		t.Fatalf("decoded header list got = %d fields, want = %d", len(got), len(hl))
+	}
+
+	for i, hf := range hl {
+		if got[i].Name() != hf.Name() || got[i].Value() != hf.Value() {
+			t.Errorf("decoded header field got = %s: %s, want = %s: %s", got[i].Name(), got[i].Value(), hf.Name(), hf.Value())
+		}
+	}
+
+	if table.EntriesCount() != len(staticTable) {
+		t.Errorf("encoded header block adds entries to index table")
+	}
+}
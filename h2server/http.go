@@ -0,0 +1,292 @@
+package h2server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/murakmii/exp-h2server/h2server/hpack"
+)
+
+type (
+	// httpHandler runs http.Handler as Handler.
+	httpHandler struct {
+		h http.Handler
+	}
+
+	// httpResponseWriter implements http.ResponseWriter on ResponseWriter.
+	httpResponseWriter struct {
+		w           ResponseWriter
+		req         *Request
+		header      http.Header
+		trailers    []string
+		wroteHeader bool
+		bodyAllowed bool
+	}
+
+	// httpRequestBody copies trailers of the request to http.Request.Trailer when it reaches EOF.
+	httpRequestBody struct {
+		io.ReadCloser
+		r       *Request
+		trailer http.Header
+		copied  bool
+	}
+)
+
+var (
+	_ Handler             = (*httpHandler)(nil)
+	_ http.ResponseWriter = (*httpResponseWriter)(nil)
+	_ http.Flusher        = (*httpResponseWriter)(nil)
+	_ http.Pusher         = (*httpResponseWriter)(nil)
+)
+
+// FromHTTPHandler returns Handler running http.Handler, so that handlers written for net/http run on h2server.
+// Response header fields prefixed by http.TrailerPrefix, and fields declared by "Trailer" header are sent as trailers.
+func FromHTTPHandler(h http.Handler) Handler {
+	return &httpHandler{h: h}
+}
+
+func (hh *httpHandler) ServeHTTP2(w ResponseWriter, r *Request) {
+	req, err := newHTTPRequest(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	rw := &httpResponseWriter{w: w, req: r, header: make(http.Header)}
+	hh.h.ServeHTTP(rw, req)
+	rw.finish()
+}
+
+// newHTTPRequest builds http.Request in the same way as net/http does for HTTP/2.
+func newHTTPRequest(r *Request) (*http.Request, error) {
+	header := make(http.Header)
+	var cookies []string
+
+	for _, hf := range r.Header {
+		// Cookie header may be split into multiple fields for better compression.
+		// See: https://tools.ietf.org/html/rfc7540#section-8.1.2.5
+		if hf.Name() == "cookie" {
+			cookies = append(cookies, hf.Value())
+			continue
+		}
+		header.Add(textproto.CanonicalMIMEHeaderKey(hf.Name()), hf.Value())
+	}
+
+	if len(cookies) > 0 {
+		header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+
+	host := r.Authority
+	if host == "" {
+		host = header.Get("Host")
+	}
+
+	var u *url.URL
+	var requestURI string
+	var err error
+
+	if r.Method == http.MethodConnect {
+		u, requestURI = &url.URL{Host: host}, host
+	} else {
+		if u, err = url.ParseRequestURI(r.Path); err != nil {
+			return nil, err
+		}
+		requestURI = r.Path
+	}
+
+	req := &http.Request{
+		Method:        r.Method,
+		URL:           u,
+		Proto:         "HTTP/2.0",
+		ProtoMajor:    2,
+		ProtoMinor:    0,
+		Header:        header,
+		Body:          r.Body,
+		ContentLength: -1,
+		Host:          host,
+		RemoteAddr:    r.RemoteAddr,
+		RequestURI:    requestURI,
+		TLS:           r.TLS,
+	}
+
+	if r.noBody {
+		req.Body = http.NoBody
+		req.ContentLength = 0
+	} else if cl := header.Get("Content-Length"); cl != "" {
+		if req.ContentLength, err = strconv.ParseInt(cl, 10, 64); err != nil || req.ContentLength < 0 {
+			return nil, fmt.Errorf("invalid content-length(%s)", cl)
+		}
+	}
+
+	// Only declared trailers are set to http.Request.Trailer as net/http does.
+	if declared := header["Trailer"]; len(declared) > 0 && !r.noBody {
+		req.Trailer = make(http.Header)
+		for _, names := range declared {
+			for _, name := range strings.Split(names, ",") {
+				if name = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name)); name != "" {
+					req.Trailer[name] = nil
+				}
+			}
+		}
+
+		header.Del("Trailer")
+		req.Body = &httpRequestBody{ReadCloser: r.Body, r: r, trailer: req.Trailer}
+	}
+
+	return req.WithContext(r.Context()), nil
+}
+
+func (body *httpRequestBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	if err == io.EOF && !body.copied {
+		body.copied = true
+
+		for _, hf := range body.r.Trailer() {
+			name := textproto.CanonicalMIMEHeaderKey(hf.Name())
+			if _, ok := body.trailer[name]; ok {
+				body.trailer[name] = append(body.trailer[name], hf.Value())
+			}
+		}
+	}
+	return n, err
+}
+
+func (rw *httpResponseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *httpResponseWriter) WriteHeader(status int) {
+	// Informational responses aren't supported, so they're ignored.
+	if rw.wroteHeader || status < 200 {
+		return
+	}
+
+	rw.wroteHeader = true
+	rw.bodyAllowed = status != http.StatusNoContent && status != http.StatusNotModified
+
+	if rw.header.Get("Date") == "" {
+		rw.header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+
+	for name, values := range rw.header {
+		if strings.HasPrefix(name, http.TrailerPrefix) {
+			continue
+		}
+
+		if name == "Trailer" {
+			for _, v := range values {
+				for _, t := range strings.Split(v, ",") {
+					if t = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(t)); t != "" {
+						rw.trailers = append(rw.trailers, t)
+					}
+				}
+			}
+		}
+
+		// Connection-specific header fields must not be sent.
+		// See: https://tools.ietf.org/html/rfc7540#section-8.1.2.2
+		switch name {
+		case "Connection", "Keep-Alive", "Proxy-Connection", "Transfer-Encoding", "Upgrade":
+			continue
+		}
+
+		for _, v := range values {
+			rw.w.AddHeader(name, v)
+		}
+	}
+
+	rw.w.WriteHeader(status)
+}
+
+func (rw *httpResponseWriter) Write(p []byte) (int, error) {
+	if !rw.wroteHeader {
+		// Content-Type is sniffed from the first write as net/http does.
+		if _, ok := rw.header["Content-Type"]; !ok && len(p) > 0 {
+			rw.header.Set("Content-Type", http.DetectContentType(p))
+		}
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	if !rw.bodyAllowed {
+		return 0, http.ErrBodyNotAllowed
+	}
+
+	// Response body of HEAD request is discarded.
+	if rw.req.Method == http.MethodHead {
+		return len(p), nil
+	}
+	return rw.w.Write(p)
+}
+
+func (rw *httpResponseWriter) Flush() {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	rw.w.Flush()
+}
+
+// Push promises the target to peer. Target must be absolute path or URL with same scheme and authority as the request.
+func (rw *httpResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if opts == nil {
+		opts = &http.PushOptions{}
+	}
+
+	method := opts.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	if !strings.HasPrefix(target, "/") {
+		u, err := url.Parse(target)
+		if err != nil {
+			return err
+		}
+
+		if u.Scheme != rw.req.Scheme || u.Host != rw.req.Authority {
+			return fmt.Errorf("can't push target(%s) with different scheme or authority", target)
+		}
+		target = u.RequestURI()
+	}
+
+	var header hpack.HeaderList
+	for name, values := range opts.Header {
+		for _, v := range values {
+			header = append(header, hpack.NewHeaderField(name, v))
+		}
+	}
+
+	if err := rw.w.Push(method, target, header); err != nil {
+		if err == ErrPushNotAllowed {
+			return http.ErrNotSupported
+		}
+		return err
+	}
+	return nil
+}
+
+// finish completes the response after http.Handler returns.
+func (rw *httpResponseWriter) finish() {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	for _, name := range rw.trailers {
+		for _, v := range rw.header[name] {
+			rw.w.AddTrailer(name, v)
+		}
+	}
+
+	for name, values := range rw.header {
+		if strings.HasPrefix(name, http.TrailerPrefix) {
+			for _, v := range values {
+				rw.w.AddTrailer(strings.TrimPrefix(name, http.TrailerPrefix), v)
+			}
+		}
+	}
+}
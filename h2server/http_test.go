@@ -0,0 +1,364 @@
+package h2server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/murakmii/exp-h2server/h2server/hpack"
+)
+
+type (
+	// httpResult is response compared between net/http server and FromHTTPHandler.
+	httpResult struct {
+		status  int
+		header  map[string]string
+		body    string
+		trailer map[string]string
+	}
+
+	httpTestRequest struct {
+		method  string
+		path    string
+		body    string
+		cookie  []string
+		trailer map[string]string
+	}
+)
+
+// comparedHeaders are response header fields compared. Others such as Date and Content-Length depend on server.
+var comparedHeaders = []string{"Content-Type", "X-Custom"}
+
+func serveStdHTTP(t *testing.T, h http.Handler, r httpTestRequest) httpResult {
+	t.Helper()
+
+	sv := httptest.NewServer(h)
+	defer sv.Close()
+
+	req, err := http.NewRequest(r.method, sv.URL+r.path, strings.NewReader(r.body))
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+
+	req.Host = "example.com"
+	if r.body == "" {
+		req.Body = nil
+	}
+	if len(r.cookie) > 0 {
+		req.Header.Set("Cookie", strings.Join(r.cookie, "; "))
+	}
+
+	// Request with trailers is chunked.
+	if len(r.trailer) > 0 {
+		req.ContentLength = -1
+		req.Trailer = make(http.Header)
+		for name, value := range r.trailer {
+			req.Trailer.Set(name, value)
+		}
+	}
+
+	resp, err := sv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+
+	result := httpResult{status: resp.StatusCode, header: map[string]string{}, body: string(body), trailer: map[string]string{}}
+	for _, name := range comparedHeaders {
+		if v := resp.Header.Get(name); v != "" {
+			result.header[name] = v
+		}
+	}
+
+	for name := range resp.Trailer {
+		result.trailer[name] = resp.Trailer.Get(name)
+	}
+	return result
+}
+
+func serveH2HTTP(t *testing.T, h http.Handler, r httpTestRequest) httpResult {
+	t.Helper()
+
+	hmp, conn := newTestMultiplexer()
+	hmp.handler = FromHTTPHandler(h)
+
+	header := hpack.HeaderList{
+		hpack.NewHeaderField(":method", r.method),
+		hpack.NewHeaderField(":scheme", "https"),
+		hpack.NewHeaderField(":authority", "example.com"),
+		hpack.NewHeaderField(":path", r.path),
+	}
+
+	for _, c := range r.cookie {
+		header = append(header, hpack.NewHeaderField("cookie", c))
+	}
+
+	var trailer hpack.HeaderList
+	for name, value := range r.trailer {
+		header = append(header, hpack.NewHeaderField("trailer", strings.ToLower(name)))
+		trailer = append(trailer, hpack.NewHeaderField(strings.ToLower(name), value))
+	}
+
+	if r.body != "" && len(trailer) == 0 {
+		header = append(header, hpack.NewHeaderField("content-length", fmt.Sprint(len(r.body))))
+	}
+
+	if err := hmp.Received(requestHeaders(t, 1, header, r.body == "")); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+
+	if r.body != "" {
+		builder := NewDataFrameBuilder(1, []byte(r.body))
+		if len(trailer) == 0 {
+			builder.EndStream()
+		}
+
+		f, err := builder.Build()
+		if err := hmp.Received(incoming(t, f, err)); err != nil {
+			t.Fatalf("Received() got error = %v", err)
+		}
+	}
+
+	if len(trailer) > 0 {
+		if err := hmp.Received(requestHeaders(t, 1, trailer, true)); err != nil {
+			t.Fatalf("Received() got error = %v", err)
+		}
+	}
+
+	waitStreamsClosed(t, hmp)
+
+	result := httpResult{header: map[string]string{}, trailer: map[string]string{}}
+	headers := 0
+
+	for _, f := range summarize(t, conn.frames()) {
+		switch f.typ {
+		case HeadersFrameType:
+			headers++
+			for _, hf := range f.header {
+				name := http.CanonicalHeaderKey(hf.Name())
+
+				switch {
+				case hf.Name() == ":status":
+					fmt.Sscan(hf.Value(), &result.status)
+
+				case headers > 1:
+					result.trailer[name] = hf.Value()
+
+				default:
+					for _, compared := range comparedHeaders {
+						if name == compared {
+							result.header[name] = hf.Value()
+						}
+					}
+				}
+			}
+
+		case DataFrameType:
+			result.body += f.data
+
+		case RstStreamFrameType:
+			t.Fatalf("stream is reset: %s", f.code)
+		}
+	}
+
+	return result
+}
+
+func TestFromHTTPHandler_Conformance(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     httpTestRequest
+		handler http.HandlerFunc
+	}{
+		{
+			name: "sniff text",
+			req:  httpTestRequest{method: "GET", path: "/"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "hello")
+			},
+		},
+		{
+			name: "sniff html",
+			req:  httpTestRequest{method: "GET", path: "/index.html"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "<!DOCTYPE html><html></html>")
+			},
+		},
+		{
+			name: "status and header",
+			req:  httpTestRequest{method: "GET", path: "/"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Custom", "value")
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, "{}")
+			},
+		},
+		{
+			name: "no content",
+			req:  httpTestRequest{method: "GET", path: "/"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+				if _, err := w.Write([]byte("ignored")); err != http.ErrBodyNotAllowed {
+					t.Errorf("Write() got error = %v, want = %v", err, http.ErrBodyNotAllowed)
+				}
+			},
+		},
+		{
+			name: "request",
+			req:  httpTestRequest{method: "POST", path: "/echo?q=1", body: "hello", cookie: []string{"a=1", "b=2"}},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				body, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					t.Errorf("Body.Read() got error = %v", err)
+				}
+
+				fmt.Fprintf(w, "%s %s %s %s %s %d %s",
+					r.Method, r.URL.Path, r.URL.Query().Get("q"), r.Host, r.Header.Get("Cookie"), r.ContentLength, body)
+			},
+		},
+		{
+			name: "request trailers",
+			req:  httpTestRequest{method: "POST", path: "/", body: "hello", trailer: map[string]string{"X-Checksum": "1234"}},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, declared := r.Trailer["X-Checksum"]
+				before := r.Trailer.Get("X-Checksum")
+
+				body, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					t.Errorf("Body.Read() got error = %v", err)
+				}
+
+				fmt.Fprintf(w, "%v %q %s %s %d", declared, before, body, r.Trailer.Get("X-Checksum"), r.ContentLength)
+			},
+		},
+		{
+			name: "request without body",
+			req:  httpTestRequest{method: "GET", path: "/"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, "%d %v", r.ContentLength, r.Body == http.NoBody)
+			},
+		},
+		{
+			name: "flush",
+			req:  httpTestRequest{method: "GET", path: "/"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "a")
+				w.(http.Flusher).Flush()
+				fmt.Fprint(w, "b")
+			},
+		},
+		{
+			name: "declared trailers",
+			req:  httpTestRequest{method: "GET", path: "/"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Trailer", "X-Checksum, X-Count")
+				fmt.Fprint(w, "body")
+				w.Header().Set("X-Checksum", "1234")
+				w.Header().Set("X-Count", "1")
+			},
+		},
+		{
+			name: "trailer prefix",
+			req:  httpTestRequest{method: "GET", path: "/"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				// net/http sends trailers only if response is chunked by flush.
+				fmt.Fprint(w, "body")
+				w.(http.Flusher).Flush()
+				w.Header().Set(http.TrailerPrefix+"X-Late", "late")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := serveStdHTTP(t, tt.handler, tt.req)
+			got := serveH2HTTP(t, tt.handler, tt.req)
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("FromHTTPHandler() responds %+v, net/http responds %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestFromHTTPHandler_Push(t *testing.T) {
+	tests := []struct {
+		name       string
+		enablePush bool
+		want       error
+	}{
+		{name: "push", enablePush: true},
+		{name: "push disabled", enablePush: false, want: http.ErrNotSupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hmp, conn := newTestMultiplexer()
+			conn.peer.EnablePush = tt.enablePush
+
+			pushErr := make(chan error, 1)
+			hmp.handler = FromHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/style.css" {
+					fmt.Fprint(w, "css")
+					return
+				}
+				pushErr <- w.(http.Pusher).Push("/style.css", nil)
+			}))
+
+			header := hpack.HeaderList{
+				hpack.NewHeaderField(":method", "GET"),
+				hpack.NewHeaderField(":scheme", "https"),
+				hpack.NewHeaderField(":authority", "example.com"),
+				hpack.NewHeaderField(":path", "/"),
+			}
+
+			if err := hmp.Received(requestHeaders(t, 1, header, true)); err != nil {
+				t.Fatalf("Received() got error = %v", err)
+			}
+
+			if err := <-pushErr; err != tt.want {
+				t.Fatalf("Push() got error = %v, want = %v", err, tt.want)
+			}
+			waitStreamsClosed(t, hmp)
+
+			var promise *PushPromiseFrame
+			var pushed string
+
+			for _, f := range conn.frames() {
+				switch in := incoming(t, f, nil).(type) {
+				case *PushPromiseFrame:
+					promise = in
+				case *DataFrame:
+					if in.StreamID() == 2 {
+						pushed += string(in.Data())
+					}
+				}
+			}
+
+			if !tt.enablePush {
+				if promise != nil {
+					t.Error("PUSH_PROMISE is sent even if push is disabled")
+				}
+				return
+			}
+
+			if promise == nil || promise.StreamID() != 1 || promise.PromisedStreamID() != 2 {
+				t.Fatalf("PUSH_PROMISE = %+v, want promised stream(2) on stream(1)", promise)
+			}
+
+			if pushed != "css" {
+				t.Errorf("pushed response = %s, want = css", pushed)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package h2server
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+type (
+	// keepalive sends PING on idle connection to check liveness and measure round trip time.
+	// It's used only by reader of the connection.
+	keepalive struct {
+		interval time.Duration
+		timeout  time.Duration
+		lastRead time.Time
+		sentAt   time.Time
+		data     [8]byte
+		waiting  bool
+	}
+)
+
+func newKeepalive(interval, timeout time.Duration, now time.Time) *keepalive {
+	return &keepalive{interval: interval, timeout: timeout, lastRead: now}
+}
+
+func (ka *keepalive) enabled() bool {
+	return ka.interval > 0
+}
+
+// deadline returns time to send PING or to give up waiting the ACK.
+func (ka *keepalive) deadline() (time.Time, bool) {
+	if !ka.enabled() {
+		return time.Time{}, false
+	}
+
+	if ka.waiting {
+		return ka.sentAt.Add(ka.timeout), true
+	}
+	return ka.lastRead.Add(ka.interval), true
+}
+
+// read records that a frame is read from the connection.
+func (ka *keepalive) read(now time.Time) {
+	ka.lastRead = now
+}
+
+// expired reports whether PING isn't acknowledged in time.
+func (ka *keepalive) expired(now time.Time) bool {
+	return ka.waiting && !now.Before(ka.sentAt.Add(ka.timeout))
+}
+
+// ping returns PING frame to send if the connection has been idle for the interval.
+// It returns nil if PING isn't needed.
+func (ka *keepalive) ping(now time.Time) (Frame, error) {
+	if !ka.enabled() || ka.waiting || now.Before(ka.lastRead.Add(ka.interval)) {
+		return nil, nil
+	}
+
+	binary.BigEndian.PutUint64(ka.data[:], uint64(now.UnixNano()))
+	ping, err := NewPingFrameBuilder(ka.data).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	ka.sentAt = now
+	ka.waiting = true
+	return ping, nil
+}
+
+// acknowledged returns round trip time if the ACK is for PING sent by keepalive.
+func (ka *keepalive) acknowledged(ack *PingFrame, now time.Time) (time.Duration, bool) {
+	if !ka.waiting || ack.Data() != ka.data {
+		return 0, false
+	}
+
+	ka.waiting = false
+	return now.Sub(ka.sentAt), true
+}
@@ -0,0 +1,86 @@
+package h2server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeepalive(t *testing.T) {
+	now := time.Now()
+	ka := newKeepalive(time.Second, 2*time.Second, now)
+
+	if deadline, ok := ka.deadline(); !ok || !deadline.Equal(now.Add(time.Second)) {
+		t.Errorf("deadline() got = %v, want = %v", deadline, now.Add(time.Second))
+	}
+
+	if ping, _ := ka.ping(now.Add(500 * time.Millisecond)); ping != nil {
+		t.Errorf("ping() returns frame before the connection becomes idle")
+	}
+
+	sentAt := now.Add(time.Second)
+	ping, err := ka.ping(sentAt)
+	if err != nil || ping == nil {
+		t.Fatalf("ping() got = %v, %v", ping, err)
+	}
+
+	if again, _ := ka.ping(sentAt.Add(time.Second)); again != nil {
+		t.Errorf("ping() returns frame while waiting ACK")
+	}
+
+	if deadline, ok := ka.deadline(); !ok || !deadline.Equal(sentAt.Add(2*time.Second)) {
+		t.Errorf("deadline() got = %v, want = %v", deadline, sentAt.Add(2*time.Second))
+	}
+
+	if ka.expired(sentAt.Add(time.Second)) {
+		t.Errorf("expired() got = true before timeout")
+	}
+
+	if !ka.expired(sentAt.Add(2 * time.Second)) {
+		t.Errorf("expired() got = false after timeout")
+	}
+
+	other, err := NewPingFrameBuilder([8]byte{1}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherACK, _ := other.(*PingFrame).ACK()
+	if _, ok := ka.acknowledged(otherACK.(*PingFrame), sentAt); ok {
+		t.Errorf("acknowledged() accepts ACK for other PING")
+	}
+
+	ack, _ := ping.(*PingFrame).ACK()
+	rtt, ok := ka.acknowledged(ack.(*PingFrame), sentAt.Add(100*time.Millisecond))
+	if !ok || rtt != 100*time.Millisecond {
+		t.Errorf("acknowledged() got = %s, %v, want = %s, true", rtt, ok, 100*time.Millisecond)
+	}
+
+	if ka.expired(sentAt.Add(time.Hour)) {
+		t.Errorf("expired() got = true after ACK")
+	}
+}
+
+func TestKeepalive_Disabled(t *testing.T) {
+	ka := newKeepalive(0, time.Second, time.Now())
+
+	if _, ok := ka.deadline(); ok {
+		t.Errorf("deadline() returns deadline while disabled")
+	}
+
+	if ping, _ := ka.ping(time.Now().Add(time.Hour)); ping != nil {
+		t.Errorf("ping() returns frame while disabled")
+	}
+}
+
+func TestPseudoConn_updateRTT(t *testing.T) {
+	pc := &pseudoConn{}
+
+	pc.updateRTT(80 * time.Millisecond)
+	if got := pc.RTT(); got != 80*time.Millisecond {
+		t.Errorf("RTT() got = %s, want = %s", got, 80*time.Millisecond)
+	}
+
+	pc.updateRTT(160 * time.Millisecond)
+	if got := pc.RTT(); got != 90*time.Millisecond {
+		t.Errorf("RTT() got = %s, want = %s", got, 90*time.Millisecond)
+	}
+}
@@ -0,0 +1,55 @@
+package h2server
+
+import (
+	"time"
+)
+
+type (
+	// connLifetime tracks idle time and age of a connection to close it.
+	// Connection is idle while it has no active streams.
+	// Idle time is measured from when reader observes active streams last, so it may be longer than actual.
+	// It's used only by reader of the connection.
+	connLifetime struct {
+		idleTimeout time.Duration
+		maxAge      time.Duration
+		createdAt   time.Time
+		lastActive  time.Time
+	}
+)
+
+func newConnLifetime(idleTimeout, maxAge time.Duration, now time.Time) *connLifetime {
+	return &connLifetime{idleTimeout: idleTimeout, maxAge: maxAge, createdAt: now, lastActive: now}
+}
+
+// observe records number of active streams observed by reader.
+func (cl *connLifetime) observe(activeStreams int, now time.Time) {
+	if activeStreams > 0 {
+		cl.lastActive = now
+	}
+}
+
+// deadline returns the earliest time to check idle time or age of the connection.
+func (cl *connLifetime) deadline() (time.Time, bool) {
+	var deadline time.Time
+	if cl.idleTimeout > 0 {
+		deadline = cl.lastActive.Add(cl.idleTimeout)
+	}
+
+	if cl.maxAge > 0 {
+		if aged := cl.createdAt.Add(cl.maxAge); deadline.IsZero() || aged.Before(deadline) {
+			deadline = aged
+		}
+	}
+
+	return deadline, !deadline.IsZero()
+}
+
+// idle reports whether the connection has been idle for the timeout.
+func (cl *connLifetime) idle(activeStreams int, now time.Time) bool {
+	return cl.idleTimeout > 0 && activeStreams == 0 && !now.Before(cl.lastActive.Add(cl.idleTimeout))
+}
+
+// aged reports whether the connection exceeds max age.
+func (cl *connLifetime) aged(now time.Time) bool {
+	return cl.maxAge > 0 && !now.Before(cl.createdAt.Add(cl.maxAge))
+}
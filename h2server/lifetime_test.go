@@ -0,0 +1,58 @@
+package h2server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConnLifetime_Idle(t *testing.T) {
+	now := time.Now()
+	cl := newConnLifetime(time.Second, 0, now)
+
+	if deadline, ok := cl.deadline(); !ok || !deadline.Equal(now.Add(time.Second)) {
+		t.Errorf("deadline() got = %v, want = %v", deadline, now.Add(time.Second))
+	}
+
+	cl.observe(1, now.Add(500*time.Millisecond))
+	if cl.idle(1, now.Add(2*time.Second)) {
+		t.Errorf("idle() got = true with active streams")
+	}
+
+	if cl.idle(0, now.Add(time.Second)) {
+		t.Errorf("idle() got = true before timeout since streams are observed")
+	}
+
+	if !cl.idle(0, now.Add(1500*time.Millisecond)) {
+		t.Errorf("idle() got = false after timeout")
+	}
+}
+
+func TestConnLifetime_Aged(t *testing.T) {
+	now := time.Now()
+	cl := newConnLifetime(time.Minute, time.Second, now)
+
+	if deadline, ok := cl.deadline(); !ok || !deadline.Equal(now.Add(time.Second)) {
+		t.Errorf("deadline() got = %v, want = %v", deadline, now.Add(time.Second))
+	}
+
+	if cl.aged(now.Add(500 * time.Millisecond)) {
+		t.Errorf("aged() got = true before max age")
+	}
+
+	if !cl.aged(now.Add(time.Second)) {
+		t.Errorf("aged() got = false after max age")
+	}
+}
+
+func TestConnLifetime_Disabled(t *testing.T) {
+	now := time.Now()
+	cl := newConnLifetime(0, 0, now)
+
+	if _, ok := cl.deadline(); ok {
+		t.Errorf("deadline() got = true when timeouts are disabled")
+	}
+
+	if cl.idle(0, now.Add(time.Hour)) || cl.aged(now.Add(time.Hour)) {
+		t.Errorf("connection is closed when timeouts are disabled")
+	}
+}
@@ -1,49 +1,901 @@
 package h2server
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sync"
+
+	"github.com/murakmii/exp-h2server/h2server/hpack"
 )
 
 type (
 	Multiplexer interface {
-		Received(Frame)
-		Terminated()
+		// Received is called for each received and verified frame.
+		// The frame and its payload are valid only until Received returns, because they may be reused for the next frame.
+		// They must be copied to retain.
+		// If it returns StreamError, the stream is reset by RST_STREAM. Other errors are treated as connection error.
+		Received(Frame) error
+
+		// Reset is called when the stream is reset by RST_STREAM because of stream error.
+		Reset(streamID uint32, code ErrorCode)
+
+		// GoAway is called when the final GOAWAY of graceful shutdown is sent.
+		// Streams initiated by peer after lastStreamID must be ignored.
+		GoAway(lastStreamID uint32)
+
+		// ActiveStreams returns number of streams not closed yet. Connection is closed by shutdown when it's zero.
+		ActiveStreams() int
+
+		// Terminated is called when the connection is terminated with the reason.
+		Terminated(t *Termination)
 	}
 
+	// HttpMultiplexer manages streams of a connection.
+	// Streams and flow control windows are guarded by mutex because they're shared with writers of streams.
 	HttpMultiplexer struct {
-		conn   Conn
-		logger Logger
+		conn               Conn
+		logger             Logger
+		handler            Handler
+		mu                 sync.Mutex
+		streams            map[uint32]*stream
+		lastClientStreamID uint32
+		lastServerStreamID uint32
+		recentlyReset      []uint32
+		recentlyClosed     []uint32
+
+		// activeStreams is number of client-initiated streams in open or half-closed state.
+		// It's limited by our SETTINGS_MAX_CONCURRENT_STREAMS.
+		activeStreams uint32
+
+		// goAwayStreamID is last stream ID sent by the final GOAWAY if goingAway is true.
+		goingAway      bool
+		goAwayStreamID uint32
+
+		// decoderTable is index table to decode header blocks.
+		// There's no table to encode because header blocks are encoded without index table.
+		decoderTable *hpack.IndexTable
+
+		// block is header block being assembled for blockStream.
+		// blockErr is stream error to return after the block is decoded to keep decoderTable synchronized.
+		// blockEndStream is whether HEADERS frame starting the block has END_STREAM flag.
+		block          headerBlock
+		blockStream    *stream
+		blockErr       error
+		blockEndStream bool
+
+		// Connection-level flow control windows, and initial window sizes applied to streams.
+		send                  sendFlow
+		recv                  recvFlow
+		peerInitialWindowSize uint32
+		initialWindowSize     uint32
+
+		// windowChanged is closed when windows for sending are changed, to wake writers waiting for them.
+		windowChanged chan struct{}
+
+		// pending is frames built while holding mu, such as WINDOW_UPDATE and RST_STREAM.
+		// They're queued by unlockAndWrite after mu is released, so that full write queue doesn't block others holding mu.
+		// writing is number of goroutines queueing frames, and it's counted as active streams until they're queued.
+		pending []Frame
+		writing int
 	}
 )
 
-func DefaultMultiplexer(logger Logger) func(Conn) Multiplexer {
+const (
+	// maxRecentlyResetStreams is number of streams to remember that the server reset.
+	// Frames on these streams are ignored because peer may send them before receiving RST_STREAM.
+	// See: https://tools.ietf.org/html/rfc7540#section-5.1
+	maxRecentlyResetStreams = 32
+
+	// maxRecentlyClosedStreams is number of client-initiated streams to remember that were opened and closed.
+	// HEADERS on other closed streams is treated as it's on streams closed implicitly without being opened,
+	// because peer can't send frames on streams closed long ago either.
+	// See: https://tools.ietf.org/html/rfc7540#section-5.1
+	maxRecentlyClosedStreams = 32
+)
+
+// DefaultMultiplexer returns HttpMultiplexer calling the handler for each request.
+// If handler is nil, NotFoundHandler is used.
+func DefaultMultiplexer(logger Logger, handler Handler) func(Conn) Multiplexer {
+	if handler == nil {
+		handler = NotFoundHandler()
+	}
+
 	return func(conn Conn) Multiplexer {
+		local, peer := conn.LocalSettings(), conn.PeerSettings()
+
 		return &HttpMultiplexer{
-			conn:   conn,
-			logger: logger,
+			conn:                  conn,
+			logger:                logger,
+			handler:               handler,
+			streams:               make(map[uint32]*stream),
+			recentlyReset:         make([]uint32, 0, maxRecentlyResetStreams),
+			recentlyClosed:        make([]uint32, 0, maxRecentlyClosedStreams),
+			decoderTable:          hpack.NewIndexTable(int(local.HeaderTableSize)),
+			send:                  newSendFlow(defaultWindowSize),
+			recv:                  newRecvFlow(defaultWindowSize),
+			peerInitialWindowSize: peer.InitialWindowSize,
+			initialWindowSize:     local.InitialWindowSize,
+			windowChanged:         make(chan struct{}),
 		}
 	}
 }
 
-func (hmp *HttpMultiplexer) Received(frame Frame) {
-	// TODO: Verify
+func (hmp *HttpMultiplexer) Received(frame Frame) error {
 	hmp.log(DebugLog, "received type=0x%X id=%d flags=0x%X payload=%d B", frame.Type(), frame.StreamID(), frame.Flags(), len(frame.Payload()))
 
+	hmp.mu.Lock()
+	err := hmp.handle(frame)
+
+	// Received doesn't have context, and Write fails when the connection is closed.
+	hmp.unlockAndWrite(context.Background())
+	return err
+}
+
+func (hmp *HttpMultiplexer) handle(frame Frame) error {
+	if err := hmp.block.verify(frame); err != nil {
+		return err
+	}
+
 	switch f := frame.(type) {
 	case *SettingsFrame:
-		hmp.handleSettings(f)
+		return hmp.handleSettings(f)
+
+	case *HeadersFrame:
+		return hmp.handleHeaders(f)
+
+	case *DataFrame:
+		return hmp.handleData(f)
+
+	case *RstStreamFrame:
+		return hmp.handleRstStream(f)
+
+	case *WindowUpdateFrame:
+		return hmp.handleWindowUpdate(f)
+
+	case *PushPromiseFrame:
+		return NewConnectionError(ProtocolError, "client must not send push promise frame")
+
+	case *ContinuationFrame:
+		// Compressed header block doesn't exceed the header list, so the block is limited by our max header list size.
+		completed, err := hmp.block.append(f.Fragment(), f.IsEndHeaders(), hmp.conn.LocalSettings().MaxHeaderListSize)
+		if err != nil {
+			return err
+		}
+
+		if completed {
+			return hmp.handleHeaderBlock()
+		}
+
+	case *PriorityFrame:
+		// PRIORITY frame can be received in any state.
+
+	case *UnknownFrame:
+		// Frames of unknown type must be ignored.
+		// See: https://tools.ietf.org/html/rfc7540#section-4.1
 	}
+
+	return nil
 }
 
-func (hmp *HttpMultiplexer) Terminated() {
+func (hmp *HttpMultiplexer) Reset(streamID uint32, code ErrorCode) {
+	hmp.log(DebugLog, "reset stream(%d): %s", streamID, code)
+
+	hmp.mu.Lock()
+	defer hmp.mu.Unlock()
 
+	if st, ok := hmp.streams[streamID]; ok {
+		hmp.closeStream(st, NewStreamError(streamID, code, "stream(%d) is reset", streamID))
+	}
+	hmp.rememberReset(streamID)
+}
+
+func (hmp *HttpMultiplexer) GoAway(lastStreamID uint32) {
+	hmp.log(DebugLog, "go away: last stream(%d)", lastStreamID)
+
+	hmp.mu.Lock()
+	defer hmp.mu.Unlock()
+
+	hmp.goingAway = true
+	hmp.goAwayStreamID = lastStreamID
+}
+
+// ActiveStreams counts streams being closed until their last frames are queued,
+// so that the connection isn't closed before writing them.
+func (hmp *HttpMultiplexer) ActiveStreams() int {
+	hmp.mu.Lock()
+	defer hmp.mu.Unlock()
+	return len(hmp.streams) + hmp.writing
+}
+
+// Terminated closes all streams to wake handlers and writers waiting for flow control windows.
+func (hmp *HttpMultiplexer) Terminated(t *Termination) {
+	hmp.log(DebugLog, "terminated: %s", t)
+
+	hmp.mu.Lock()
+	defer hmp.mu.Unlock()
+
+	for _, st := range hmp.streams {
+		hmp.closeStream(st, ErrConnClosed)
+	}
+}
+
+// unlockAndWrite releases mu, and queues pending frames and the frames in this order.
+// Pending frames are queued without ctx, because they're built on behalf of other streams or the connection.
+func (hmp *HttpMultiplexer) unlockAndWrite(ctx context.Context, frames ...Frame) error {
+	pending := hmp.pending
+	hmp.pending = nil
+
+	if len(pending) == 0 && len(frames) == 0 {
+		hmp.mu.Unlock()
+		return nil
+	}
+
+	hmp.writing++
+	hmp.mu.Unlock()
+
+	defer func() {
+		hmp.mu.Lock()
+		hmp.writing--
+		hmp.mu.Unlock()
+	}()
+
+	for _, f := range pending {
+		if err := hmp.conn.Write(context.Background(), f); err != nil {
+			hmp.log(DebugLog, "failed to write frame(0x%X) on stream(%d): %s", f.Type(), f.StreamID(), err.Error())
+		}
+	}
+
+	return hmp.conn.Write(ctx, frames...)
 }
 
 func (hmp *HttpMultiplexer) log(level LogLevel, format string, args ...interface{}) {
 	hmp.logger.Write(level, fmt.Sprintf("<%s> ", hmp.conn.RemoteAddr().String())+format, args...)
 }
 
-func (hmp *HttpMultiplexer) handleSettings(f *SettingsFrame) {
-	// TODO: Verify
+// stream returns stream of the ID.
+// Streams not tracked are idle or closed, and are not tracked by returning them.
+func (hmp *HttpMultiplexer) stream(id uint32) *stream {
+	if st, ok := hmp.streams[id]; ok {
+		return st
+	}
+
+	last := hmp.lastServerStreamID
+	if isClientStreamID(id) {
+		last = hmp.lastClientStreamID
+	}
+
+	// Idle streams with lower ID than opened stream are closed implicitly.
+	// See: https://tools.ietf.org/html/rfc7540#section-5.1.1
+	if id <= last {
+		return newStream(id, StreamClosed)
+	}
+	return newStream(id, StreamIdle)
+}
+
+// gc stops tracking the stream if it's closed.
+func (hmp *HttpMultiplexer) gc(st *stream) {
+	if !st.isClosed() {
+		return
+	}
+
+	if _, ok := hmp.streams[st.id]; ok {
+		delete(hmp.streams, st.id)
+		if isClientStreamID(st.id) {
+			hmp.activeStreams--
+			hmp.recentlyClosed = rememberStreamID(hmp.recentlyClosed, st.id, maxRecentlyClosedStreams)
+		}
+	}
+}
+
+// closeStream closes the stream abnormally, and wakes the handler reading the request or waiting for windows.
+func (hmp *HttpMultiplexer) closeStream(st *stream, err error) {
+	st.close()
+	hmp.gc(st)
+
+	if st.body != nil {
+		st.body.finish(err)
+	}
+	if st.cancel != nil {
+		st.cancel()
+	}
+	hmp.notifyWindowChanged()
+}
+
+func (hmp *HttpMultiplexer) rememberReset(id uint32) {
+	hmp.recentlyReset = rememberStreamID(hmp.recentlyReset, id, maxRecentlyResetStreams)
+}
+
+func (hmp *HttpMultiplexer) isRecentlyReset(id uint32) bool {
+	return containsStreamID(hmp.recentlyReset, id)
+}
+
+// rememberStreamID appends the ID to the list, forgetting the oldest one if the list has max IDs.
+func rememberStreamID(ids []uint32, id uint32, max int) []uint32 {
+	if len(ids) == max {
+		ids = append(ids[:0], ids[1:]...)
+	}
+	return append(ids, id)
+}
+
+func containsStreamID(ids []uint32, id uint32) bool {
+	for _, remembered := range ids {
+		if remembered == id {
+			return true
+		}
+	}
+	return false
+}
+
+// isIgnored reports whether frames on the closed stream are ignored,
+// because the server reset it recently or peer initiated it after the final GOAWAY.
+// See: https://tools.ietf.org/html/rfc7540#section-6.8
+func (hmp *HttpMultiplexer) isIgnored(id uint32) bool {
+	if hmp.goingAway && isClientStreamID(id) && id > hmp.goAwayStreamID {
+		return true
+	}
+	return hmp.isRecentlyReset(id)
+}
+
+func (hmp *HttpMultiplexer) notifyWindowChanged() {
+	close(hmp.windowChanged)
+	hmp.windowChanged = make(chan struct{})
+}
+
+// handleSettings applies settings to index table and flow control windows of streams.
+// Parameters have been verified and applied to Conn already.
+// Peer's settings are applied when received, and our settings are applied when acknowledged.
+func (hmp *HttpMultiplexer) handleSettings(f *SettingsFrame) error {
+	if f.IsACK() {
+		local := hmp.conn.LocalSettings()
+		hmp.decoderTable.UpdateMaxProtocolDataSize(int(local.HeaderTableSize))
+
+		if local.InitialWindowSize != hmp.initialWindowSize {
+			hmp.initialWindowSize = local.InitialWindowSize
+			for _, st := range hmp.streams {
+				st.recv.resize(local.InitialWindowSize)
+			}
+		}
+		return nil
+	}
+
+	peer := hmp.conn.PeerSettings()
+
+	// Change of initial window size is applied to all streams retroactively.
+	// See: https://tools.ietf.org/html/rfc7540#section-6.9.2
+	delta := int64(peer.InitialWindowSize) - int64(hmp.peerInitialWindowSize)
+	hmp.peerInitialWindowSize = peer.InitialWindowSize
+
+	if delta == 0 {
+		return nil
+	}
+
+	for _, st := range hmp.streams {
+		if !st.send.add(delta) {
+			return NewConnectionError(FlowControlError, "initial window size makes window of stream(%d) overflow", st.id)
+		}
+	}
+
+	hmp.notifyWindowChanged()
+	return nil
+}
+
+// handleHeaders changes state of the stream and starts assembling header block.
+// Header block is decoded even if the stream can't receive it, to keep decoderTable synchronized with peer.
+func (hmp *HttpMultiplexer) handleHeaders(f *HeadersFrame) error {
+	st := hmp.stream(f.StreamID())
+	discard := false
+	var err error
+
+	switch st.state {
+	case StreamIdle:
+		if !isClientStreamID(st.id) {
+			return NewConnectionError(ProtocolError, "client can't open stream(%d) with even ID", st.id)
+		}
+		hmp.lastClientStreamID = st.id
+
+		if hmp.isIgnored(st.id) {
+			discard = true
+			break
+		}
+
+		// Streams beyond the limit are refused, so that peer can retry them safely.
+		// See: https://tools.ietf.org/html/rfc7540#section-5.1.2
+		if limit := hmp.conn.LocalSettings().MaxConcurrentStreams; hmp.activeStreams >= limit {
+			err = NewStreamError(st.id, RefusedStreamError, "stream(%d) exceeds max concurrent streams(%d)", st.id, limit)
+			discard = true
+			break
+		}
+
+		st.send = newSendFlow(hmp.peerInitialWindowSize)
+		st.recv = newRecvFlow(hmp.initialWindowSize)
+		hmp.streams[st.id] = st
+		hmp.activeStreams++
+
+	case StreamClosed:
+		if _, ok := hmp.streams[st.id]; ok {
+			break
+		}
+
+		if hmp.isIgnored(st.id) {
+			discard = true
+			break
+		}
+
+		// Client can't open stream with lower ID than streams opened already.
+		// See: https://tools.ietf.org/html/rfc7540#section-5.1.1
+		if isClientStreamID(st.id) && !containsStreamID(hmp.recentlyClosed, st.id) {
+			return NewConnectionError(ProtocolError, "received headers frame on stream(%d) closed implicitly", st.id)
+		}
+		return NewConnectionError(StreamClosedError, "received headers frame on closed stream(%d)", st.id)
+	}
+
+	if !discard {
+		var connErr *ConnectionError
+		if err = st.recvHeaders(f.IsEOS()); errors.As(err, &connErr) {
+			return err
+		}
+		hmp.gc(st)
+	}
+
+	hmp.blockStream, hmp.blockErr, hmp.blockEndStream = st, err, f.IsEOS()
+	if discard {
+		hmp.blockStream = nil
+	}
+
+	completed, err := hmp.block.start(st.id, f.Fragment(), f.IsEndHeaders(), hmp.conn.LocalSettings().MaxHeaderListSize)
+	if err != nil {
+		return err
+	}
+
+	if completed {
+		return hmp.handleHeaderBlock()
+	}
+	return nil
+}
+
+// handleHeaderBlock decodes completed header block and passes it to the stream.
+func (hmp *HttpMultiplexer) handleHeaderBlock() error {
+	st, blockErr, endStream := hmp.blockStream, hmp.blockErr, hmp.blockEndStream
+	hmp.blockStream, hmp.blockErr, hmp.blockEndStream = nil, nil, false
+
+	headerList, err := hmp.block.decode(hmp.decoderTable)
+
+	var connErr *ConnectionError
+	if errors.As(err, &connErr) {
+		return err
+	}
+
+	if blockErr != nil || st == nil {
+		return blockErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	hmp.log(DebugLog, "received %d header fields on stream(%d)", len(headerList), st.id)
+
+	if st.body == nil {
+		if err := validateRequestHeader(st.id, headerList); err != nil {
+			return err
+		}
+
+		st.header = headerList
+		hmp.startHandler(st)
+		return nil
+	}
+
+	// Header block received after the request is trailers, and it must end the stream.
+	// State of the stream isn't enough to check it because the stream may be half-closed (local) by the response.
+	// See: https://tools.ietf.org/html/rfc7540#section-8.1
+	if !endStream {
+		return NewStreamError(st.id, ProtocolError, "trailers of stream(%d) don't end the stream", st.id)
+	}
+
+	if err := validateTrailer(st.id, headerList); err != nil {
+		return err
+	}
+
+	st.body.finishTrailer(headerList)
+	return nil
+}
+
+// startHandler calls the handler on a goroutine for the request of the stream.
+func (hmp *HttpMultiplexer) startHandler(st *stream) {
+	ctx, cancel := context.WithCancel(context.Background())
+	st.body = newRequestBody(func(n int, discarded bool) {
+		hmp.bodyConsumed(st, int64(n), discarded)
+	})
+	st.cancel = cancel
+
+	req := newRequest(ctx, st.id, st.header, st.body)
+	req.RemoteAddr = hmp.conn.RemoteAddr().String()
+	req.TLS = hmp.conn.ConnectionState()
+
+	// Request without DATA frames, such as the request ended by HEADERS and promised request.
+	if st.state != StreamOpen {
+		st.body.finish(io.EOF)
+		req.noBody = true
+	}
+
+	go hmp.serve(ctx, st, req)
+}
+
+// serve calls the handler and completes the response. If the handler panics, the stream is reset by INTERNAL_ERROR.
+func (hmp *HttpMultiplexer) serve(ctx context.Context, st *stream, req *Request) {
+	rw := newResponseWriter(ctx, hmp, st, req)
+
+	defer func() {
+		if r := recover(); r != nil {
+			hmp.log(ErrorLog, "handler of stream(%d) panics: %v", st.id, r)
+			hmp.resetStream(st, InternalError)
+		}
+
+		// Request body not read by the handler is discarded, and credited to the connection.
+		st.body.Close()
+
+		hmp.mu.Lock()
+		st.cancel()
+		hmp.mu.Unlock()
+	}()
+
+	hmp.handler.ServeHTTP2(rw, req)
+
+	err := rw.finish()
+	if err != nil {
+		hmp.log(DebugLog, "failed to finish response of stream(%d): %s", st.id, err.Error())
+	}
+	hmp.stopReceiving(st, err == nil)
+}
+
+// stopReceiving resets the stream if peer is still sending the request after the handler returns.
+// NO_ERROR is used if the response is completed, so that peer stops sending only the request.
+// See: https://tools.ietf.org/html/rfc7540#section-8.1
+func (hmp *HttpMultiplexer) stopReceiving(st *stream, completed bool) {
+	hmp.mu.Lock()
+
+	switch {
+	case completed && st.state == StreamHalfClosedLocal:
+		hmp.reset(st, NoError)
+
+	case !completed:
+		hmp.reset(st, CancelError)
+	}
+
+	hmp.unlockAndWrite(context.Background())
+}
+
+// resetStream resets the stream by RST_STREAM on behalf of the handler.
+func (hmp *HttpMultiplexer) resetStream(st *stream, code ErrorCode) {
+	hmp.mu.Lock()
+	hmp.reset(st, code)
+	hmp.unlockAndWrite(context.Background())
+}
+
+func (hmp *HttpMultiplexer) reset(st *stream, code ErrorCode) {
+	if st.isClosed() {
+		return
+	}
+
+	rst, err := NewRstStreamFrameBuilder(st.id, code).Build()
+	if err != nil {
+		hmp.log(ErrorLog, "failed to generate rst stream frame: %s", err.Error())
+		return
+	}
+
+	hmp.pending = append(hmp.pending, rst)
+	hmp.closeStream(st, NewStreamError(st.id, code, "stream(%d) is reset", st.id))
+	hmp.rememberReset(st.id)
+}
+
+// handleData receives DATA frame within flow control windows.
+// Entire payload including padding counts against the windows.
+// See: https://tools.ietf.org/html/rfc7540#section-6.1
+func (hmp *HttpMultiplexer) handleData(f *DataFrame) error {
+	n := int64(len(f.Payload()))
+	if !hmp.recv.receive(n) {
+		return NewConnectionError(FlowControlError, "data frame(%d octets) exceeds connection window", n)
+	}
+
+	st := hmp.stream(f.StreamID())
+	if st.isClosed() && hmp.isIgnored(st.id) {
+		hmp.consume(nil, n)
+		return nil
+	}
+
+	if err := st.recvData(f.IsEOS()); err != nil {
+		hmp.consume(nil, n)
+		return err
+	}
+
+	if !st.recv.receive(n) {
+		hmp.consume(nil, n)
+		return NewStreamError(st.id, FlowControlError, "data frame(%d octets) exceeds window of stream(%d)", n, st.id)
+	}
+
+	// Data is credited when the handler reads it, so buffer of the body is bounded by the window of the stream.
+	// Padding and data the handler doesn't read are consumed immediately.
+	data := f.Data()
+	if st.body != nil && st.body.write(data) {
+		hmp.consume(st, n-int64(len(data)))
+	} else {
+		hmp.consume(nil, n)
+	}
+
+	if st.body != nil && f.IsEOS() {
+		st.body.finish(io.EOF)
+	}
+
+	hmp.gc(st)
+	return nil
+}
+
+// bodyConsumed credits data read from the request body by the handler.
+// Discarded data is credited only to the connection, so that peer can't send more on the stream.
+func (hmp *HttpMultiplexer) bodyConsumed(st *stream, n int64, discarded bool) {
+	hmp.mu.Lock()
+
+	if discarded {
+		hmp.consume(nil, n)
+	} else {
+		hmp.consume(st, n)
+	}
+
+	hmp.unlockAndWrite(context.Background())
+}
+
+// consume credits consumed data back to peer by WINDOW_UPDATE.
+// If st is nil, the data is discarded and credited only to the connection.
+func (hmp *HttpMultiplexer) consume(st *stream, n int64) {
+	if inc := hmp.recv.consume(n); inc > 0 {
+		hmp.writeWindowUpdate(0x00, inc)
+	}
+
+	// Stream doesn't need WINDOW_UPDATE after peer finishes sending.
+	if st == nil || st.state == StreamHalfClosedRemote || st.isClosed() {
+		return
+	}
+
+	if inc := st.recv.consume(n); inc > 0 {
+		hmp.writeWindowUpdate(st.id, inc)
+	}
+}
+
+// writeWindowUpdate builds WINDOW_UPDATE frame to be written after mu is released.
+func (hmp *HttpMultiplexer) writeWindowUpdate(streamID uint32, inc uint32) {
+	windowUpdate, err := NewWindowUpdateFrameBuilder(streamID, inc).Build()
+	if err != nil {
+		hmp.log(ErrorLog, "failed to generate window update frame: %s", err.Error())
+		return
+	}
+
+	hmp.pending = append(hmp.pending, windowUpdate)
+}
+
+func (hmp *HttpMultiplexer) handleRstStream(f *RstStreamFrame) error {
+	st := hmp.stream(f.StreamID())
+	if st.state == StreamIdle {
+		return NewConnectionError(ProtocolError, "received rst stream frame on idle stream(%d)", st.id)
+	}
+
+	hmp.log(DebugLog, "stream(%d) is reset by peer: %s", st.id, f.ErrorCode())
+	hmp.closeStream(st, NewStreamError(st.id, f.ErrorCode(), "stream(%d) is reset by peer", st.id))
+	return nil
+}
+
+// handleWindowUpdate increases windows for sending and wakes writers waiting for them.
+// See: https://tools.ietf.org/html/rfc7540#section-6.9.1
+func (hmp *HttpMultiplexer) handleWindowUpdate(f *WindowUpdateFrame) error {
+	inc := int64(f.WindowSizeIncrement())
+
+	if f.StreamID() == 0x00 {
+		if !hmp.send.add(inc) {
+			return NewConnectionError(FlowControlError, "window update makes connection window overflow")
+		}
+
+		hmp.notifyWindowChanged()
+		return nil
+	}
+
+	st := hmp.stream(f.StreamID())
+	if err := st.verifyRecv(f.Type()); err != nil {
+		return err
+	}
+
+	if _, ok := hmp.streams[st.id]; !ok {
+		return nil
+	}
+
+	if !st.send.add(inc) {
+		return NewStreamError(st.id, FlowControlError, "window update makes window of stream(%d) overflow", st.id)
+	}
+
+	hmp.notifyWindowChanged()
+	return nil
+}
+
+// writeData sends data on the stream as DATA frames within flow control windows.
+// It blocks until peer opens windows, the stream is closed or ctx is done.
+// Each frame is queued after mu is released, so that full write queue doesn't block receiving frames.
+// Padding decided by PaddingPolicy of Conn also counts against the windows.
+func (hmp *HttpMultiplexer) writeData(ctx context.Context, st *stream, data []byte, endStream bool) error {
+	for {
+		hmp.mu.Lock()
+
+		if st.state != StreamOpen && st.state != StreamHalfClosedRemote {
+			hmp.mu.Unlock()
+			return NewStreamError(st.id, StreamClosedError, "can't send data frame on %s stream(%d)", st.state, st.id)
+		}
+
+		window := int64(hmp.conn.PeerSettings().MaxFrameSize)
+		if available := hmp.send.available(); available < window {
+			window = available
+		}
+		if available := st.send.available(); available < window {
+			window = available
+		}
+
+		if window <= 0 && len(data) > 0 {
+			changed := hmp.windowChanged
+			hmp.mu.Unlock()
+
+			select {
+			case <-changed:
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if window < 0 {
+			window = 0
+		}
+
+		chunk := data
+		if int64(len(chunk)) > window {
+			chunk = chunk[:window]
+		}
+		last := len(chunk) == len(data)
+
+		// Data is copied because the caller may reuse it before the frame is written.
+		builder := NewDataFrameBuilder(st.id, append([]byte(nil), chunk...)).
+			PaddingPolicy(hmp.conn.PaddingPolicy()).
+			MaxFrameSize(uint32(window))
+		if last && endStream {
+			builder.EndStream()
+		}
+
+		f, err := builder.Build()
+		if err != nil {
+			hmp.mu.Unlock()
+			return err
+		}
+
+		n := int64(len(f.Payload()))
+		hmp.send.take(n)
+		st.send.take(n)
+
+		if err := st.sendData(last && endStream); err != nil {
+			hmp.mu.Unlock()
+			return err
+		}
+
+		hmp.gc(st)
+		if err := hmp.unlockAndWrite(ctx, f); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+		data = data[len(chunk):]
+	}
+}
+
+// writeHeaders sends header list on the stream as HEADERS and CONTINUATION frames.
+// Header block is encoded without index table, so that blocks of streams don't depend on order of writing.
+func (hmp *HttpMultiplexer) writeHeaders(ctx context.Context, st *stream, headerList hpack.HeaderList, endStream bool) error {
+	hmp.mu.Lock()
+
+	builder := NewHeadersFrameBuilder(st.id, headerList.Encode()).
+		PaddingPolicy(hmp.conn.PaddingPolicy()).
+		MaxFrameSize(hmp.conn.PeerSettings().MaxFrameSize)
+	if endStream {
+		builder.EndStream()
+	}
+
+	frames, err := builder.Build()
+	if err == nil {
+		err = st.sendHeaders(endStream)
+	}
+
+	if err != nil {
+		hmp.mu.Unlock()
+		return err
+	}
+
+	hmp.gc(st)
+	return hmp.unlockAndWrite(ctx, frames...)
+}
+
+// push reserves new stream by PUSH_PROMISE on the parent stream, and calls the handler for the promised request.
+// See: https://tools.ietf.org/html/rfc7540#section-8.2
+func (hmp *HttpMultiplexer) push(ctx context.Context, parent *stream, headerList hpack.HeaderList) error {
+	hmp.mu.Lock()
+
+	st, frames, err := hmp.reservePush(parent, headerList)
+	if err != nil {
+		hmp.mu.Unlock()
+		return err
+	}
+
+	// PUSH_PROMISE is queued before the handler starts, so that it precedes the response of the promised stream.
+	err = hmp.unlockAndWrite(ctx, frames...)
+
+	hmp.mu.Lock()
+	defer hmp.mu.Unlock()
+
+	if err != nil {
+		hmp.closeStream(st, err)
+		return err
+	}
+
+	// Peer may reset the promised stream as soon as it receives PUSH_PROMISE.
+	if st.isClosed() {
+		return nil
+	}
+
+	hmp.log(DebugLog, "promised stream(%d) on stream(%d)", st.id, parent.id)
+	hmp.startHandler(st)
+	return nil
+}
+
+// reservePush reserves new stream for the push, and builds PUSH_PROMISE frames for it.
+func (hmp *HttpMultiplexer) reservePush(parent *stream, headerList hpack.HeaderList) (*stream, []Frame, error) {
+	peer := hmp.conn.PeerSettings()
+	if !peer.EnablePush || hmp.goingAway {
+		return nil, nil, ErrPushNotAllowed
+	}
+
+	// PUSH_PROMISE can be sent only on client-initiated stream we don't end yet.
+	if !isClientStreamID(parent.id) || (parent.state != StreamOpen && parent.state != StreamHalfClosedRemote) {
+		return nil, nil, fmt.Errorf("can't push on %s stream(%d)", parent.state, parent.id)
+	}
+
+	var pushed uint32
+	for id := range hmp.streams {
+		if !isClientStreamID(id) {
+			pushed++
+		}
+	}
+
+	if pushed >= peer.MaxConcurrentStreams {
+		return nil, nil, fmt.Errorf("pushed streams exceed max concurrent streams(%d)", peer.MaxConcurrentStreams)
+	}
+
+	id := hmp.lastServerStreamID + 2
+	if id > maxStreamID {
+		return nil, nil, fmt.Errorf("stream ID for push is exhausted")
+	}
+
+	frames, err := NewPushPromiseFrameBuilder(parent.id, id, headerList.Encode()).
+		PaddingPolicy(hmp.conn.PaddingPolicy()).
+		MaxFrameSize(peer.MaxFrameSize).
+		Build()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	st := newStream(id, StreamIdle)
+	if err := st.sendPushPromise(); err != nil {
+		return nil, nil, err
+	}
 
+	hmp.lastServerStreamID = id
+	st.send = newSendFlow(hmp.peerInitialWindowSize)
+	st.recv = newRecvFlow(hmp.initialWindowSize)
+	st.header = headerList
+	hmp.streams[id] = st
+	return st, frames, nil
 }
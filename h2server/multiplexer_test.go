@@ -0,0 +1,889 @@
+package h2server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testConn struct {
+	mu      sync.Mutex
+	written []Frame
+	local   Settings
+	peer    Settings
+
+	// If blocked isn't nil, Write blocks until it's closed as the write queue is full.
+	blocked chan struct{}
+}
+
+var (
+	_ Conn = (*testConn)(nil)
+)
+
+func (c *testConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 443}
+}
+
+func (c *testConn) ConnectionState() *tls.ConnectionState {
+	return nil
+}
+
+func (c *testConn) PaddingPolicy() PaddingPolicy {
+	return NoPadding()
+}
+
+func (c *testConn) LocalSettings() Settings {
+	return c.local
+}
+
+func (c *testConn) PeerSettings() Settings {
+	return c.peer
+}
+
+func (c *testConn) RTT() time.Duration {
+	return 0
+}
+
+func (c *testConn) WriteStats() WriteStats {
+	return WriteStats{}
+}
+
+func (c *testConn) UpdateSettings(ctx context.Context, settings *SettingsFrameBuilder) error {
+	return nil
+}
+
+func (c *testConn) Write(ctx context.Context, frames ...Frame) error {
+	if c.blocked != nil {
+		select {
+		case <-c.blocked:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.written = append(c.written, frames...)
+	return nil
+}
+
+func (c *testConn) Flush(ctx context.Context) error {
+	return nil
+}
+
+// frames returns written frames and clears them.
+func (c *testConn) frames() []Frame {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	written := c.written
+	c.written = nil
+	return written
+}
+
+func (c *testConn) Close() {}
+
+// incoming converts outgoing frame to incoming one as it's received from peer.
+func incoming(t *testing.T, f Frame, err error) IncomingFrame {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("failed to build frame: %v", err)
+	}
+
+	in, err := Read(bytes.NewReader(appendFrame(nil, f)))
+	if err != nil {
+		t.Fatalf("failed to read frame: %v", err)
+	}
+	return in
+}
+
+// testRequestBlock is header block of minimum request. (:method: GET, :scheme: https, :path: /)
+var testRequestBlock = []byte{0x82, 0x87, 0x84}
+
+// waitingHandler doesn't respond until the stream is closed, so that tests observe only frames they send.
+func waitingHandler() Handler {
+	return HandlerFunc(func(w ResponseWriter, r *Request) {
+		<-r.Context().Done()
+	})
+}
+
+func newTestMultiplexer() (*HttpMultiplexer, *testConn) {
+	conn := &testConn{local: DefaultSettings(), peer: DefaultSettings()}
+	return DefaultMultiplexer(NullLogger(), waitingHandler())(conn).(*HttpMultiplexer), conn
+}
+
+func TestHttpMultiplexer_Received(t *testing.T) {
+	headers := func(t *testing.T, id uint32, endStream bool) IncomingFrame {
+		builder := NewHeadersFrameBuilder(id, testRequestBlock)
+		if endStream {
+			builder.EndStream()
+		}
+
+		frames, err := builder.Build()
+		return incoming(t, frames[0], err)
+	}
+
+	data := func(t *testing.T, id uint32, endStream bool) IncomingFrame {
+		builder := NewDataFrameBuilder(id, []byte("data"))
+		if endStream {
+			builder.EndStream()
+		}
+
+		f, err := builder.Build()
+		return incoming(t, f, err)
+	}
+
+	rst := func(t *testing.T, id uint32) IncomingFrame {
+		f, err := NewRstStreamFrameBuilder(id, CancelError).Build()
+		return incoming(t, f, err)
+	}
+
+	windowUpdate := func(t *testing.T, id uint32) IncomingFrame {
+		f, err := NewWindowUpdateFrameBuilder(id, 1).Build()
+		return incoming(t, f, err)
+	}
+
+	pushPromise := func(t *testing.T, id uint32) IncomingFrame {
+		frames, err := NewPushPromiseFrameBuilder(id, id+1, nil).Build()
+		return incoming(t, frames[0], err)
+	}
+
+	priority := func(t *testing.T, id uint32) IncomingFrame {
+		f, err := NewPriorityFrameBuilder(id).Build()
+		return incoming(t, f, err)
+	}
+
+	type want struct {
+		streamErr ErrorCode
+		connErr   ErrorCode
+	}
+
+	tests := []struct {
+		name   string
+		frames []func(*testing.T) IncomingFrame
+		reset  []uint32
+		want   want
+	}{
+		{
+			name: "request and body",
+			frames: []func(*testing.T) IncomingFrame{
+				func(t *testing.T) IncomingFrame { return headers(t, 1, false) },
+				func(t *testing.T) IncomingFrame { return data(t, 1, true) },
+				func(t *testing.T) IncomingFrame { return windowUpdate(t, 1) },
+			},
+		},
+		{
+			name: "data after end stream",
+			frames: []func(*testing.T) IncomingFrame{
+				func(t *testing.T) IncomingFrame { return headers(t, 1, true) },
+				func(t *testing.T) IncomingFrame { return data(t, 1, false) },
+			},
+			want: want{streamErr: StreamClosedError},
+		},
+		{
+			name: "headers after end stream",
+			frames: []func(*testing.T) IncomingFrame{
+				func(t *testing.T) IncomingFrame { return headers(t, 1, true) },
+				func(t *testing.T) IncomingFrame { return headers(t, 1, true) },
+			},
+			want: want{streamErr: StreamClosedError},
+		},
+		{
+			name: "data on idle stream",
+			frames: []func(*testing.T) IncomingFrame{
+				func(t *testing.T) IncomingFrame { return data(t, 1, false) },
+			},
+			want: want{connErr: ProtocolError},
+		},
+		{
+			name: "rst stream on idle stream",
+			frames: []func(*testing.T) IncomingFrame{
+				func(t *testing.T) IncomingFrame { return rst(t, 3) },
+			},
+			want: want{connErr: ProtocolError},
+		},
+		{
+			name: "window update on idle stream",
+			frames: []func(*testing.T) IncomingFrame{
+				func(t *testing.T) IncomingFrame { return windowUpdate(t, 3) },
+			},
+			want: want{connErr: ProtocolError},
+		},
+		{
+			name: "priority on idle stream",
+			frames: []func(*testing.T) IncomingFrame{
+				func(t *testing.T) IncomingFrame { return priority(t, 3) },
+			},
+		},
+		{
+			name: "stream opened with even ID",
+			frames: []func(*testing.T) IncomingFrame{
+				func(t *testing.T) IncomingFrame { return headers(t, 2, false) },
+			},
+			want: want{connErr: ProtocolError},
+		},
+		{
+			name: "stream opened with lower ID",
+			frames: []func(*testing.T) IncomingFrame{
+				func(t *testing.T) IncomingFrame { return headers(t, 5, false) },
+				func(t *testing.T) IncomingFrame { return headers(t, 3, false) },
+			},
+			want: want{connErr: ProtocolError},
+		},
+		{
+			name: "headers on stream closed after opened",
+			frames: []func(*testing.T) IncomingFrame{
+				func(t *testing.T) IncomingFrame { return headers(t, 1, false) },
+				func(t *testing.T) IncomingFrame { return rst(t, 1) },
+				func(t *testing.T) IncomingFrame { return headers(t, 1, false) },
+			},
+			want: want{connErr: StreamClosedError},
+		},
+		{
+			name: "data on stream closed implicitly",
+			frames: []func(*testing.T) IncomingFrame{
+				func(t *testing.T) IncomingFrame { return headers(t, 5, false) },
+				func(t *testing.T) IncomingFrame { return data(t, 3, false) },
+			},
+			want: want{streamErr: StreamClosedError},
+		},
+		{
+			name: "data after rst stream",
+			frames: []func(*testing.T) IncomingFrame{
+				func(t *testing.T) IncomingFrame { return headers(t, 1, false) },
+				func(t *testing.T) IncomingFrame { return rst(t, 1) },
+				func(t *testing.T) IncomingFrame { return data(t, 1, false) },
+			},
+			want: want{streamErr: StreamClosedError},
+		},
+		{
+			name: "data on stream reset by server",
+			frames: []func(*testing.T) IncomingFrame{
+				func(t *testing.T) IncomingFrame { return headers(t, 1, false) },
+				func(t *testing.T) IncomingFrame { return data(t, 1, false) },
+			},
+			reset: []uint32{1},
+		},
+		{
+			name: "push promise",
+			frames: []func(*testing.T) IncomingFrame{
+				func(t *testing.T) IncomingFrame { return headers(t, 1, false) },
+				func(t *testing.T) IncomingFrame { return pushPromise(t, 1) },
+			},
+			want: want{connErr: ProtocolError},
+		},
+	}
+
+	for _, tt := range tests {
+		hmp, _ := newTestMultiplexer()
+
+		var err error
+		for i, f := range tt.frames {
+			err = hmp.Received(f(t))
+			if i == 0 {
+				for _, id := range tt.reset {
+					hmp.Reset(id, InternalError)
+				}
+			}
+
+			if err != nil {
+				break
+			}
+		}
+
+		var streamErr *StreamError
+		var connErr *ConnectionError
+		switch {
+		case tt.want.streamErr != NoError:
+			if !errors.As(err, &streamErr) || streamErr.Code() != tt.want.streamErr {
+				t.Errorf("%s: Received() got error = %v, want stream error %s", tt.name, err, tt.want.streamErr)
+			}
+		case tt.want.connErr != NoError:
+			if !errors.As(err, &connErr) || connErr.Code() != tt.want.connErr {
+				t.Errorf("%s: Received() got error = %v, want connection error %s", tt.name, err, tt.want.connErr)
+			}
+		case err != nil:
+			t.Errorf("%s: Received() got error = %v", tt.name, err)
+		}
+	}
+}
+
+func TestHttpMultiplexer_ClosedStreamsAreRemoved(t *testing.T) {
+	hmp, _ := newTestMultiplexer()
+
+	frames, err := NewHeadersFrameBuilder(1, testRequestBlock).Build()
+	if err := hmp.Received(incoming(t, frames[0], err)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+
+	if st, ok := hmp.streams[1]; !ok || st.state != StreamOpen {
+		t.Fatalf("stream(1) isn't open: %+v", st)
+	}
+
+	rst, err := NewRstStreamFrameBuilder(1, CancelError).Build()
+	if err := hmp.Received(incoming(t, rst, err)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+
+	if _, ok := hmp.streams[1]; ok {
+		t.Errorf("closed stream(1) is still tracked")
+	}
+
+	if got := hmp.stream(1).state; got != StreamClosed {
+		t.Errorf("stream(1) got state = %s, want = %s", got, StreamClosed)
+	}
+}
+
+func TestHttpMultiplexer_Reset(t *testing.T) {
+	hmp, _ := newTestMultiplexer()
+
+	for id := uint32(1); id <= maxRecentlyResetStreams+1; id++ {
+		hmp.Reset(id, CancelError)
+	}
+
+	if hmp.isRecentlyReset(1) {
+		t.Errorf("the oldest reset stream is still remembered")
+	}
+
+	if !hmp.isRecentlyReset(maxRecentlyResetStreams + 1) {
+		t.Errorf("the latest reset stream isn't remembered")
+	}
+}
+
+func TestHttpMultiplexer_Settings(t *testing.T) {
+	hmp, conn := newTestMultiplexer()
+
+	frames, err := NewHeadersFrameBuilder(1, testRequestBlock).Build()
+	if err := hmp.Received(incoming(t, frames[0], err)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+
+	conn.peer.HeaderTableSize = 256
+	conn.peer.InitialWindowSize = 1024
+
+	settings, err := NewSettingsFrameBuilder().Add(
+		&SettingsFrameParam{ID: HeaderTableSizeSetting, Value: 256},
+		&SettingsFrameParam{ID: InitialWindowSizeSetting, Value: 1024},
+	).Build()
+	if err := hmp.Received(incoming(t, settings, err)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+
+	if got := hmp.streams[1].send.available(); got != 1024 {
+		t.Errorf("send.available() got = %d, want = %d", got, 1024)
+	}
+}
+
+func TestHttpMultiplexer_FlowControl_Recv(t *testing.T) {
+	type want struct {
+		streamErr    ErrorCode
+		connErr      ErrorCode
+		windowUpdate map[uint32]uint32
+	}
+
+	tests := []struct {
+		name       string
+		connWindow uint32
+		initial    uint32
+		data       []int
+		read       bool
+		want       want
+	}{
+		{
+			name:       "window update after half of window is read",
+			connWindow: defaultWindowSize,
+			initial:    defaultWindowSize,
+			data:       []int{16384, 16384},
+			read:       true,
+			want:       want{windowUpdate: map[uint32]uint32{0: 32768, 1: 32768}},
+		},
+		{
+			name:       "no window update until handler reads",
+			connWindow: defaultWindowSize,
+			initial:    defaultWindowSize,
+			data:       []int{16384, 16384},
+			want:       want{windowUpdate: map[uint32]uint32{}},
+		},
+		{
+			name:       "stream window exceeded",
+			connWindow: defaultWindowSize,
+			initial:    10,
+			data:       []int{11},
+			want:       want{streamErr: FlowControlError},
+		},
+		{
+			name:       "connection window exceeded",
+			connWindow: 10,
+			initial:    defaultWindowSize,
+			data:       []int{11},
+			want:       want{connErr: FlowControlError},
+		},
+	}
+
+	for _, tt := range tests {
+		conn := &testConn{local: DefaultSettings(), peer: DefaultSettings()}
+		conn.local.InitialWindowSize = tt.initial
+		hmp := DefaultMultiplexer(NullLogger(), waitingHandler())(conn).(*HttpMultiplexer)
+		hmp.recv = newRecvFlow(tt.connWindow)
+
+		read := make(chan struct{})
+		if tt.read {
+			total := 0
+			for _, n := range tt.data {
+				total += n
+			}
+
+			hmp.handler = HandlerFunc(func(w ResponseWriter, r *Request) {
+				io.ReadFull(r.Body, make([]byte, total))
+				close(read)
+				<-r.Context().Done()
+			})
+		}
+
+		frames, err := NewHeadersFrameBuilder(1, testRequestBlock).Build()
+		if err := hmp.Received(incoming(t, frames[0], err)); err != nil {
+			t.Fatalf("%s: Received() got error = %v", tt.name, err)
+		}
+
+		for _, n := range tt.data {
+			data, buildErr := NewDataFrameBuilder(1, make([]byte, n)).Build()
+			if err = hmp.Received(incoming(t, data, buildErr)); err != nil {
+				break
+			}
+		}
+
+		if tt.read {
+			<-read
+		}
+
+		var streamErr *StreamError
+		var connErr *ConnectionError
+		switch {
+		case tt.want.streamErr != NoError:
+			if !errors.As(err, &streamErr) || streamErr.Code() != tt.want.streamErr {
+				t.Errorf("%s: Received() got error = %v, want stream error %s", tt.name, err, tt.want.streamErr)
+			}
+		case tt.want.connErr != NoError:
+			if !errors.As(err, &connErr) || connErr.Code() != tt.want.connErr {
+				t.Errorf("%s: Received() got error = %v, want connection error %s", tt.name, err, tt.want.connErr)
+			}
+		case err != nil:
+			t.Errorf("%s: Received() got error = %v", tt.name, err)
+		}
+
+		got := make(map[uint32]uint32)
+		for _, f := range conn.frames() {
+			if windowUpdate, ok := f.(*WindowUpdateFrame); ok {
+				got[windowUpdate.StreamID()] += windowUpdate.WindowSizeIncrement()
+			}
+		}
+
+		if len(got) != len(tt.want.windowUpdate) {
+			t.Errorf("%s: window updates got = %v, want = %v", tt.name, got, tt.want.windowUpdate)
+			continue
+		}
+		for id, inc := range tt.want.windowUpdate {
+			if got[id] != inc {
+				t.Errorf("%s: window updates got = %v, want = %v", tt.name, got, tt.want.windowUpdate)
+			}
+		}
+	}
+}
+
+func TestHttpMultiplexer_FlowControl_WindowUpdateOverflow(t *testing.T) {
+	tests := []struct {
+		streamID  uint32
+		streamErr bool
+	}{
+		{streamID: 0, streamErr: false},
+		{streamID: 1, streamErr: true},
+	}
+
+	for _, tt := range tests {
+		hmp, _ := newTestMultiplexer()
+
+		frames, err := NewHeadersFrameBuilder(1, testRequestBlock).Build()
+		if err := hmp.Received(incoming(t, frames[0], err)); err != nil {
+			t.Fatalf("Received() got error = %v", err)
+		}
+
+		windowUpdate, err := NewWindowUpdateFrameBuilder(tt.streamID, maxWindowSize).Build()
+		err = hmp.Received(incoming(t, windowUpdate, err))
+
+		var streamErr *StreamError
+		if UnwrapErrorCode(err) != FlowControlError || errors.As(err, &streamErr) != tt.streamErr {
+			t.Errorf("window update on stream(%d) got error = %v", tt.streamID, err)
+		}
+	}
+}
+
+func TestHttpMultiplexer_writeData(t *testing.T) {
+	conn := &testConn{local: DefaultSettings(), peer: DefaultSettings()}
+	conn.peer.InitialWindowSize = 100
+	hmp := DefaultMultiplexer(NullLogger(), waitingHandler())(conn).(*HttpMultiplexer)
+
+	frames, err := NewHeadersFrameBuilder(1, testRequestBlock).EndStream().Build()
+	if err := hmp.Received(incoming(t, frames[0], err)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+	st := hmp.streams[1]
+
+	if err := hmp.writeData(context.Background(), st, make([]byte, 100), false); err != nil {
+		t.Fatalf("writeData() got error = %v", err)
+	}
+
+	// Decreasing initial window size makes the window negative.
+	conn.peer.InitialWindowSize = 50
+	settings, err := NewSettingsFrameBuilder().Add(&SettingsFrameParam{ID: InitialWindowSizeSetting, Value: 50}).Build()
+	if err := hmp.Received(incoming(t, settings, err)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+
+	if got := st.send.available(); got != -50 {
+		t.Fatalf("send.available() got = %d, want = %d", got, -50)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- hmp.writeData(context.Background(), st, make([]byte, 10), true)
+	}()
+
+	for _, inc := range []uint32{50, 10} {
+		select {
+		case err := <-done:
+			t.Fatalf("writeData() returns while window is exhausted: %v", err)
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		windowUpdate, err := NewWindowUpdateFrameBuilder(1, inc).Build()
+		if err := hmp.Received(incoming(t, windowUpdate, err)); err != nil {
+			t.Fatalf("Received() got error = %v", err)
+		}
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("writeData() got error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("writeData() is blocked after window is opened")
+	}
+
+	var sent []int
+	for _, f := range conn.frames() {
+		if data, ok := f.(*DataFrame); ok {
+			sent = append(sent, len(data.Payload()))
+		}
+	}
+
+	if len(sent) != 2 || sent[0] != 100 || sent[1] != 10 {
+		t.Errorf("sent data frames got = %v, want = [100 10]", sent)
+	}
+
+	if st.state != StreamClosed {
+		t.Errorf("stream state got = %s, want = %s", st.state, StreamClosed)
+	}
+}
+
+func TestHttpMultiplexer_writeData_Blocked(t *testing.T) {
+	hmp, conn := newTestMultiplexer()
+
+	frames, err := NewHeadersFrameBuilder(1, testRequestBlock).EndStream().Build()
+	if err := hmp.Received(incoming(t, frames[0], err)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+	st := hmp.streams[1]
+
+	conn.blocked = make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- hmp.writeData(context.Background(), st, make([]byte, 10), true)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		hmp.mu.Lock()
+		writing := hmp.writing
+		hmp.mu.Unlock()
+
+		if writing > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("writeData() doesn't write data frame")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Stream is closed by END_STREAM, but it's active until the last frame is queued.
+	if got := hmp.ActiveStreams(); got != 1 {
+		t.Errorf("ActiveStreams() got = %d, want = 1 while writing", got)
+	}
+
+	windowUpdate, err := NewWindowUpdateFrameBuilder(0, 10).Build()
+	f := incoming(t, windowUpdate, err)
+
+	received := make(chan error, 1)
+	go func() {
+		received <- hmp.Received(f)
+	}()
+
+	select {
+	case err := <-received:
+		if err != nil {
+			t.Fatalf("Received() got error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Received() is blocked by writeData()")
+	}
+
+	close(conn.blocked)
+	if err := <-done; err != nil {
+		t.Fatalf("writeData() got error = %v", err)
+	}
+
+	if got := hmp.ActiveStreams(); got != 0 {
+		t.Errorf("ActiveStreams() got = %d, want = 0", got)
+	}
+}
+
+func TestHttpMultiplexer_writeData_Split(t *testing.T) {
+	hmp, conn := newTestMultiplexer()
+	hmp.send = newSendFlow(20000)
+
+	frames, err := NewHeadersFrameBuilder(1, testRequestBlock).Build()
+	if err := hmp.Received(incoming(t, frames[0], err)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+	st := hmp.streams[1]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := hmp.writeData(ctx, st, make([]byte, 30000), true); err != context.DeadlineExceeded {
+		t.Errorf("writeData() got error = %v, want = %v", err, context.DeadlineExceeded)
+	}
+
+	var sent []int
+	for _, f := range conn.frames() {
+		sent = append(sent, len(f.Payload()))
+	}
+
+	if len(sent) != 2 || sent[0] != defaultMaxFrameSize || sent[1] != 20000-defaultMaxFrameSize {
+		t.Errorf("sent data frames got = %v", sent)
+	}
+}
+
+func TestHttpMultiplexer_HeaderBlock(t *testing.T) {
+	headers := func(id uint32, flags uint8, fragment ...byte) Frame {
+		return &HeadersFrame{frame: &frame{typ: HeadersFrameType, flags: flags, streamID: id, payload: fragment}}
+	}
+
+	continuation := func(id uint32, flags uint8, fragment ...byte) Frame {
+		return &ContinuationFrame{frame: &frame{typ: ContinuationFrameType, flags: flags, streamID: id, payload: fragment}}
+	}
+
+	data := &DataFrame{frame: &frame{typ: DataFrameType, streamID: 1, payload: []byte("data")}}
+
+	// :authority: example.com (indexed in dynamic table)
+	authority := []byte{0x41, 0x0b, 'e', 'x', 'a', 'm', 'p', 'l', 'e', '.', 'c', 'o', 'm'}
+
+	type want struct {
+		streamErr ErrorCode
+		connErr   ErrorCode
+		header    map[uint32]int
+	}
+
+	tests := []struct {
+		name   string
+		frames []Frame
+		want   want
+	}{
+		{
+			name: "headers and continuation",
+			frames: []Frame{
+				headers(1, 0x00, 0x82, 0x87),
+				continuation(1, 0x00, 0x84),
+				continuation(1, 0x04, authority...),
+			},
+			want: want{header: map[uint32]int{1: 4}},
+		},
+		{
+			name: "interleaved by data",
+			frames: []Frame{
+				headers(1, 0x00, 0x82),
+				data,
+			},
+			want: want{connErr: ProtocolError},
+		},
+		{
+			name: "continuation on other stream",
+			frames: []Frame{
+				headers(1, 0x00, 0x82),
+				continuation(3, 0x04, 0x84),
+			},
+			want: want{connErr: ProtocolError},
+		},
+		{
+			name: "continuation without headers",
+			frames: []Frame{
+				continuation(1, 0x04, 0x84),
+			},
+			want: want{connErr: ProtocolError},
+		},
+		{
+			name: "invalid header block",
+			frames: []Frame{
+				headers(1, 0x04, 0xbf),
+			},
+			want: want{connErr: CompressionError},
+		},
+		{
+			name: "header block on half-closed stream is decoded",
+			frames: []Frame{
+				headers(1, 0x05, testRequestBlock...),
+				headers(1, 0x04, authority...),
+			},
+			want: want{streamErr: StreamClosedError},
+		},
+		{
+			name: "malformed request",
+			frames: []Frame{
+				headers(1, 0x04, 0x82, 0x84),
+			},
+			want: want{streamErr: ProtocolError},
+		},
+		{
+			name: "upper case header name",
+			frames: []Frame{
+				headers(1, 0x04, 0x82, 0x87, 0x84, 0x00, 0x01, 'A', 0x01, 'a'),
+			},
+			want: want{streamErr: ProtocolError},
+		},
+	}
+
+	for _, tt := range tests {
+		hmp, _ := newTestMultiplexer()
+
+		var err error
+		for _, f := range tt.frames {
+			if err = hmp.Received(incoming(t, f, nil)); err != nil {
+				break
+			}
+		}
+
+		var streamErr *StreamError
+		var connErr *ConnectionError
+		switch {
+		case tt.want.streamErr != NoError:
+			if !errors.As(err, &streamErr) || streamErr.Code() != tt.want.streamErr {
+				t.Errorf("%s: Received() got error = %v, want stream error %s", tt.name, err, tt.want.streamErr)
+			}
+		case tt.want.connErr != NoError:
+			if !errors.As(err, &connErr) || connErr.Code() != tt.want.connErr {
+				t.Errorf("%s: Received() got error = %v, want connection error %s", tt.name, err, tt.want.connErr)
+			}
+		case err != nil:
+			t.Errorf("%s: Received() got error = %v", tt.name, err)
+		}
+
+		for id, n := range tt.want.header {
+			if st, ok := hmp.streams[id]; !ok || len(st.header) != n {
+				t.Errorf("%s: header of stream(%d) got = %+v, want %d fields", tt.name, id, st, n)
+			}
+		}
+	}
+}
+
+func TestHttpMultiplexer_HeaderBlock_DecodedOnResetStream(t *testing.T) {
+	hmp, _ := newTestMultiplexer()
+
+	// :authority: example.com is indexed in dynamic table though the stream is reset.
+	block := []byte{0x82, 0x87, 0x84, 0x41, 0x0b, 'e', 'x', 'a', 'm', 'p', 'l', 'e', '.', 'c', 'o', 'm'}
+	first, err := NewHeadersFrameBuilder(1, testRequestBlock).Build()
+	if err := hmp.Received(incoming(t, first[0], err)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+	hmp.Reset(1, CancelError)
+
+	reset, err := NewHeadersFrameBuilder(1, block).EndStream().Build()
+	if err := hmp.Received(incoming(t, reset[0], err)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+
+	// Index 62 refers to :authority: example.com
+	next, err := NewHeadersFrameBuilder(3, []byte{0x82, 0x87, 0x84, 0xbe}).Build()
+	if err := hmp.Received(incoming(t, next[0], err)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+
+	header := hmp.streams[3].header
+	if len(header) != 4 || header[3].Name() != ":authority" || header[3].Value() != "example.com" {
+		t.Errorf("header of stream(3) got = %+v", header)
+	}
+}
+
+func TestHttpMultiplexer_MaxConcurrentStreams(t *testing.T) {
+	hmp, conn := newTestMultiplexer()
+	conn.local.MaxConcurrentStreams = 1
+
+	headers := func(t *testing.T, id uint32, block []byte) IncomingFrame {
+		frames, err := NewHeadersFrameBuilder(id, block).Build()
+		return incoming(t, frames[0], err)
+	}
+
+	if err := hmp.Received(headers(t, 1, testRequestBlock)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+
+	// :authority: example.com is indexed in dynamic table though the stream is refused.
+	block := []byte{0x82, 0x87, 0x84, 0x41, 0x0b, 'e', 'x', 'a', 'm', 'p', 'l', 'e', '.', 'c', 'o', 'm'}
+	err := hmp.Received(headers(t, 3, block))
+
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) || streamErr.StreamID() != 3 || streamErr.Code() != RefusedStreamError {
+		t.Fatalf("Received() got error = %v, want = refused stream error", err)
+	}
+	hmp.Reset(3, RefusedStreamError)
+
+	if _, ok := hmp.streams[3]; ok || hmp.activeStreams != 1 {
+		t.Fatalf("refused stream(3) is tracked: active = %d", hmp.activeStreams)
+	}
+
+	// Data on refused stream is ignored.
+	data, err := NewDataFrameBuilder(3, []byte("data")).Build()
+	if err := hmp.Received(incoming(t, data, err)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+
+	rst, err := NewRstStreamFrameBuilder(1, CancelError).Build()
+	if err := hmp.Received(incoming(t, rst, err)); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+
+	// Index 62 refers to :authority: example.com
+	if err := hmp.Received(headers(t, 5, []byte{0x82, 0x87, 0x84, 0xbe})); err != nil {
+		t.Fatalf("Received() got error = %v", err)
+	}
+
+	if header := hmp.streams[5].header; len(header) != 4 || header[3].Value() != "example.com" {
+		t.Errorf("header of stream(5) got = %+v", header)
+	}
+
+	// Limit is changed at runtime.
+	conn.local.MaxConcurrentStreams = 2
+	if err := hmp.Received(headers(t, 7, testRequestBlock)); err != nil {
+		t.Errorf("Received() got error = %v after the limit is raised", err)
+	}
+
+	if hmp.activeStreams != 2 {
+		t.Errorf("active streams got = %d, want = %d", hmp.activeStreams, 2)
+	}
+}
@@ -0,0 +1,118 @@
+package h2server
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+)
+
+type (
+	// PaddingPolicy decides length of padding for outgoing DATA, HEADERS and PUSH_PROMISE frames.
+	// Padding of DATA frame counts against flow control window as well as data.
+	// See: https://tools.ietf.org/html/rfc7540#section-10.7
+	PaddingPolicy interface {
+		// PadLength returns length of padding for payload of dataLen octets other than Pad Length field and padding.
+		// It's data of DATA frame, or header block fragment and other fields of HEADERS and PUSH_PROMISE frame.
+		// Returned length must not exceed maxPadLen. If padded is false, the frame isn't padded at all.
+		PadLength(dataLen int, maxPadLen int) (padLen uint8, padded bool)
+	}
+
+	noPadding struct{}
+
+	blockPadding struct {
+		blockSize int
+	}
+
+	randomPadding struct {
+		min int
+		max int
+	}
+)
+
+const (
+	maxPadLen = 255
+)
+
+var (
+	_ PaddingPolicy = (*noPadding)(nil)
+	_ PaddingPolicy = (*blockPadding)(nil)
+	_ PaddingPolicy = (*randomPadding)(nil)
+)
+
+func NoPadding() PaddingPolicy {
+	return &noPadding{}
+}
+
+func (*noPadding) PadLength(_ int, _ int) (uint8, bool) {
+	return 0, false
+}
+
+// BlockPadding pads frames so that length of payload is multiple of blockSize.
+// If it requires padding longer than allowed, the frame is padded as long as possible.
+func BlockPadding(blockSize int) PaddingPolicy {
+	if blockSize < 2 {
+		return NoPadding()
+	}
+
+	return &blockPadding{blockSize: blockSize}
+}
+
+func (block *blockPadding) PadLength(dataLen int, maxPadLen int) (uint8, bool) {
+	if maxPadLen < 0 {
+		return 0, false
+	}
+
+	// Pad Length field(1 octet) is also part of payload.
+	padLen := (block.blockSize - (dataLen+1)%block.blockSize) % block.blockSize
+	return uint8(minInt(padLen, maxPadLen)), true
+}
+
+// RandomPadding pads frames with random length between min and max.
+func RandomPadding(min int, max int) PaddingPolicy {
+	if min > max {
+		min, max = max, min
+	}
+
+	return &randomPadding{
+		min: minInt(maxInt(min, 0), maxPadLen),
+		max: minInt(maxInt(max, 0), maxPadLen),
+	}
+}
+
+func (random *randomPadding) PadLength(_ int, maxPadLen int) (uint8, bool) {
+	if maxPadLen < 0 {
+		return 0, false
+	}
+
+	var buf [2]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return uint8(minInt(random.max, maxPadLen)), true
+	}
+
+	padLen := random.min + int(binary.BigEndian.Uint16(buf[:]))%(random.max-random.min+1)
+	return uint8(minInt(padLen, maxPadLen)), true
+}
+
+// applyPadding returns length of padding decided by policy.
+// otherLen is length of fields other than data such as priority, and they're padded together with data.
+// maxPayloadLen is upper bound of payload length, including data, other fields and padding.
+func applyPadding(policy PaddingPolicy, dataLen int, otherLen int, maxPayloadLen int) (uint8, bool) {
+	if policy == nil {
+		return 0, false
+	}
+
+	return policy.PadLength(otherLen+dataLen, minInt(maxPadLen, maxPayloadLen-otherLen-dataLen-1))
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
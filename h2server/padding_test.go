@@ -0,0 +1,157 @@
+package h2server
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBlockPadding_PadLength(t *testing.T) {
+	tests := []struct {
+		blockSize  int
+		dataLen    int
+		maxPadLen  int
+		wantLen    uint8
+		wantPadded bool
+	}{
+		{blockSize: 16, dataLen: 15, maxPadLen: 255, wantLen: 0, wantPadded: true},
+		{blockSize: 16, dataLen: 0, maxPadLen: 255, wantLen: 15, wantPadded: true},
+		{blockSize: 16, dataLen: 20, maxPadLen: 255, wantLen: 11, wantPadded: true},
+		{blockSize: 16, dataLen: 20, maxPadLen: 5, wantLen: 5, wantPadded: true},
+		{blockSize: 16, dataLen: 20, maxPadLen: -1, wantLen: 0, wantPadded: false},
+		{blockSize: 1024, dataLen: 0, maxPadLen: 255, wantLen: 255, wantPadded: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("block=%d,data=%d,max=%d", tt.blockSize, tt.dataLen, tt.maxPadLen), func(t *testing.T) {
+			gotLen, gotPadded := BlockPadding(tt.blockSize).PadLength(tt.dataLen, tt.maxPadLen)
+			if gotLen != tt.wantLen || gotPadded != tt.wantPadded {
+				t.Errorf("PadLength() got = (%d, %v), want = (%d, %v)", gotLen, gotPadded, tt.wantLen, tt.wantPadded)
+			}
+		})
+	}
+}
+
+func TestRandomPadding_PadLength(t *testing.T) {
+	policy := RandomPadding(10, 20)
+	for i := 0; i < 100; i++ {
+		got, padded := policy.PadLength(100, 255)
+		if !padded || got < 10 || got > 20 {
+			t.Fatalf("PadLength() got = (%d, %v), want between 10 and 20", got, padded)
+		}
+	}
+
+	if got, _ := policy.PadLength(100, 5); got > 5 {
+		t.Errorf("PadLength() got = %d, want <= 5", got)
+	}
+}
+
+func TestNoPadding_PadLength(t *testing.T) {
+	if _, padded := NoPadding().PadLength(100, 255); padded {
+		t.Errorf("PadLength() got padded")
+	}
+}
+
+func TestDataFrameBuilder_PaddingPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *DataFrameBuilder
+		wantLen int
+	}{
+		{
+			name:    "block",
+			builder: NewDataFrameBuilder(1, make([]byte, 10)).PaddingPolicy(BlockPadding(64)),
+			wantLen: 64,
+		},
+		{
+			name:    "no_room",
+			builder: NewDataFrameBuilder(1, make([]byte, defaultMaxFrameSize)).PaddingPolicy(BlockPadding(64)),
+			wantLen: defaultMaxFrameSize,
+		},
+		{
+			name:    "limited_by_max_frame_size",
+			builder: NewDataFrameBuilder(1, make([]byte, defaultMaxFrameSize-10)).PaddingPolicy(RandomPadding(255, 255)),
+			wantLen: defaultMaxFrameSize,
+		},
+		{
+			name:    "explicit",
+			builder: NewDataFrameBuilder(1, make([]byte, 10)).Padding(1).PaddingPolicy(BlockPadding(64)),
+			wantLen: 12,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := tt.builder.Build()
+			if err != nil {
+				t.Fatalf("Build() got error = %v", err)
+			}
+
+			if len(f.Payload()) != tt.wantLen {
+				t.Errorf("Build() got payload length = %d, want = %d", len(f.Payload()), tt.wantLen)
+			}
+
+			data := f.(*DataFrame)
+			if err := data.Verify(); err != nil {
+				t.Errorf("Verify() of built frame got error = %v", err)
+			}
+			if len(data.Data()) != len(tt.builder.data) {
+				t.Errorf("Data() got length = %d, want = %d", len(data.Data()), len(tt.builder.data))
+			}
+		})
+	}
+}
+
+func TestHeadersFrameBuilder_PaddingPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		builder    *HeadersFrameBuilder
+		payloadLen int
+		fragLen    int
+	}{
+		{
+			name:       "block padding",
+			builder:    NewHeadersFrameBuilder(1, make([]byte, 10)).PaddingPolicy(BlockPadding(32)),
+			payloadLen: 32,
+			fragLen:    10,
+		},
+		{
+			name:       "block padding with priority",
+			builder:    NewHeadersFrameBuilder(1, make([]byte, 10)).Priority(0, false, 16).PaddingPolicy(BlockPadding(32)),
+			payloadLen: 32,
+			fragLen:    10,
+		},
+		{
+			name:       "block padding of block exceeding max frame size",
+			builder:    NewHeadersFrameBuilder(1, make([]byte, defaultMaxFrameSize+10)).PaddingPolicy(BlockPadding(32)),
+			payloadLen: defaultMaxFrameSize,
+			fragLen:    defaultMaxFrameSize - 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames, err := tt.builder.Build()
+			if err != nil {
+				t.Fatalf("Build() got error = %v", err)
+			}
+
+			headers := incoming(t, frames[0], nil).(*HeadersFrame)
+			if !headers.IsPadded() || len(headers.Payload()) != tt.payloadLen || len(headers.Fragment()) != tt.fragLen {
+				t.Errorf("Build() got payload(%d octets) with fragment(%d octets), want = %d, %d",
+					len(headers.Payload()), len(headers.Fragment()), tt.payloadLen, tt.fragLen)
+			}
+		})
+	}
+}
+
+func TestPushPromiseFrameBuilder_PaddingPolicy(t *testing.T) {
+	frames, err := NewPushPromiseFrameBuilder(1, 2, make([]byte, 10)).PaddingPolicy(BlockPadding(32)).Build()
+	if err != nil {
+		t.Fatalf("Build() got error = %v", err)
+	}
+
+	promise := incoming(t, frames[0], nil).(*PushPromiseFrame)
+	if len(promise.Payload()) != 32 || !promise.IsPadded() || len(promise.HeaderFragment()) != 10 || promise.PromisedStreamID() != 2 {
+		t.Errorf("Build() got payload(%d octets) with fragment(%d octets), want = 32, 10", len(promise.Payload()), len(promise.HeaderFragment()))
+	}
+}
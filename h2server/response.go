@@ -0,0 +1,129 @@
+package h2server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/murakmii/exp-h2server/h2server/hpack"
+)
+
+type (
+	// responseWriter sends response of a stream by HttpMultiplexer.
+	// It's used only by goroutine of the handler.
+	responseWriter struct {
+		hmp         *HttpMultiplexer
+		st          *stream
+		req         *Request
+		ctx         context.Context
+		header      hpack.HeaderList
+		trailer     hpack.HeaderList
+		wroteHeader bool
+	}
+)
+
+var (
+	_ ResponseWriter = (*responseWriter)(nil)
+)
+
+func newResponseWriter(ctx context.Context, hmp *HttpMultiplexer, st *stream, req *Request) *responseWriter {
+	return &responseWriter{hmp: hmp, st: st, req: req, ctx: ctx}
+}
+
+func (rw *responseWriter) AddHeader(name, value string) {
+	if !rw.wroteHeader {
+		rw.header = append(rw.header, hpack.NewHeaderField(strings.ToLower(name), value))
+	}
+}
+
+func (rw *responseWriter) WriteHeader(status int) error {
+	return rw.writeHeader(status, false)
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	if !rw.wroteHeader {
+		if err := rw.WriteHeader(200); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if err := rw.hmp.writeData(rw.ctx, rw.st, p, false); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (rw *responseWriter) Flush() error {
+	return rw.hmp.conn.Flush(rw.ctx)
+}
+
+func (rw *responseWriter) DeclareTrailer(name string) {
+	rw.AddHeader("trailer", strings.ToLower(name))
+}
+
+func (rw *responseWriter) AddTrailer(name, value string) {
+	if !strings.HasPrefix(name, ":") {
+		rw.trailer = append(rw.trailer, hpack.NewHeaderField(strings.ToLower(name), value))
+	}
+}
+
+func (rw *responseWriter) Push(method, path string, header hpack.HeaderList) error {
+	// Promised requests must be safe and cacheable, and can't have body.
+	// See: https://tools.ietf.org/html/rfc7231#section-4.2
+	if method != "GET" && method != "HEAD" {
+		return fmt.Errorf("can't push request with method(%s)", method)
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("can't push request with path(%s)", path)
+	}
+
+	headerList := hpack.HeaderList{
+		hpack.NewHeaderField(":method", method),
+		hpack.NewHeaderField(":scheme", rw.req.Scheme),
+		hpack.NewHeaderField(":authority", rw.req.Authority),
+		hpack.NewHeaderField(":path", path),
+	}
+
+	for _, hf := range header {
+		headerList = append(headerList, hpack.NewHeaderField(strings.ToLower(hf.Name()), hf.Value()))
+	}
+	return rw.hmp.push(rw.ctx, rw.st, headerList)
+}
+
+// writeHeader sends HEADERS with :status and header fields added so far.
+// Informational responses(1xx) aren't supported.
+// See: https://tools.ietf.org/html/rfc7540#section-8.1
+func (rw *responseWriter) writeHeader(status int, endStream bool) error {
+	if rw.wroteHeader {
+		return fmt.Errorf("response header of stream(%d) is already written", rw.st.id)
+	}
+
+	if status < 200 || status > 999 {
+		return fmt.Errorf("invalid status code(%d)", status)
+	}
+
+	headerList := append(hpack.HeaderList{hpack.NewHeaderField(":status", strconv.Itoa(status))}, rw.header...)
+	rw.wroteHeader = true
+	return rw.hmp.writeHeaders(rw.ctx, rw.st, headerList, endStream)
+}
+
+// finish ends the stream after handler returns.
+// The stream is ended by trailers if they're added, or by empty DATA frame.
+func (rw *responseWriter) finish() error {
+	if !rw.wroteHeader {
+		if err := rw.writeHeader(200, len(rw.trailer) == 0); err != nil || len(rw.trailer) == 0 {
+			return err
+		}
+	}
+
+	if len(rw.trailer) > 0 {
+		return rw.hmp.writeHeaders(rw.ctx, rw.st, rw.trailer, true)
+	}
+	return rw.hmp.writeData(rw.ctx, rw.st, nil, true)
+}
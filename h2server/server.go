@@ -6,18 +6,51 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type (
 	Server struct {
-		logger  Logger
-		cert    tls.Certificate
-		addr    string
-		preface func() *SettingsFrameBuilder
-		mp      func(Conn) Multiplexer
+		logger   Logger
+		cert     tls.Certificate
+		addr     string
+		preface  func() *SettingsFrameBuilder
+		mp       func(Conn) Multiplexer
+		registry *FrameRegistry
+		altSvc   func() []*AltSvcFrameBuilder
+		origins  []string
+		padding  PaddingPolicy
+
+		settingsTimeout   time.Duration
+		keepaliveInterval time.Duration
+		keepaliveTimeout  time.Duration
+		handshakeTimeout  time.Duration
+		prefaceTimeout    time.Duration
+		idleTimeout       time.Duration
+		maxConnectionAge  time.Duration
+		connState         func(net.Conn, ConnState)
+		stats             ServerStats
+
+		// mu guards listener and connections to shut down.
+		// shutdown is closed when Shutdown or Close is called.
+		mu       sync.Mutex
+		listener net.Listener
+		conns    map[net.Conn]struct{}
+		shutdown chan struct{}
+	}
+
+	// ServerStats is statistics of events on all connections of the server.
+	ServerStats struct {
+		// RefusedStreams is number of streams refused by RST_STREAM(REFUSED_STREAM).
+		RefusedStreams uint64
+
+		// Writes is statistics of frames written on closed connections.
+		Writes WriteStats
 	}
 
 	ServerConfig struct {
@@ -25,16 +58,92 @@ type (
 		Certificate tls.Certificate
 		Address     string
 		Preface     func() *SettingsFrameBuilder
+
+		// Handler responds to requests. If nil, HTTPHandler or NotFoundHandler is used.
+		Handler Handler
+
+		// HTTPHandler is http.Handler used through FromHTTPHandler if Handler is nil.
+		HTTPHandler http.Handler
+
+		// Multiplexer handles frames of each connection. If nil, DefaultMultiplexer calling Handler is used.
 		Multiplexer func(Conn) Multiplexer
+
+		// FrameRegistry is used to decode extension frames.
+		// If nil, all extension frames are passed to Multiplexer as UnknownFrame.
+		FrameRegistry *FrameRegistry
+
+		// AltSvc returns builders of ALTSVC frames sent on stream ID(0) after server preface.
+		AltSvc func() []*AltSvcFrameBuilder
+
+		// Origins are sent by ORIGIN frame after server preface to allow clients to coalesce connections.
+		// OriginsFromCertificate is useful to list origins the certificate is valid for.
+		Origins []string
+
+		// Padding is policy for padding of outgoing DATA, HEADERS and PUSH_PROMISE frames.
+		// If nil, frames aren't padded.
+		Padding PaddingPolicy
+
+		// SettingsTimeout is time to wait for peer to acknowledge our SETTINGS frame.
+		// If peer doesn't acknowledge it in time, the connection is closed with SETTINGS_TIMEOUT.
+		// If zero, defaultSettingsTimeout is used.
+		SettingsTimeout time.Duration
+
+		// KeepaliveInterval is idle time of connection before sending PING to check liveness and measure RTT.
+		// If zero, keepalive is disabled.
+		KeepaliveInterval time.Duration
+
+		// KeepaliveTimeout is time to wait for ACK of keepalive PING. Connection is closed if it's not acknowledged.
+		// If zero, defaultKeepaliveTimeout is used.
+		KeepaliveTimeout time.Duration
+
+		// HandshakeTimeout is time to complete TLS handshake. If zero, defaultHandshakeTimeout is used.
+		HandshakeTimeout time.Duration
+
+		// PrefaceTimeout is time to exchange connection prefaces after TLS handshake.
+		// If zero, defaultPrefaceTimeout is used.
+		PrefaceTimeout time.Duration
+
+		// IdleTimeout is time to close connection without active streams by GOAWAY(NO_ERROR).
+		// If zero, idle connections aren't closed.
+		IdleTimeout time.Duration
+
+		// MaxConnectionAge is time to shut down connection gracefully as Shutdown does.
+		// If zero, connections aren't closed by their age.
+		MaxConnectionAge time.Duration
+
+		// ConnState is called when state of connection changes.
+		// Active and idle states are changed when reader of the connection observes number of active streams.
+		ConnState func(net.Conn, ConnState)
 	}
 )
 
 const (
 	alpnH2 = "h2"
+
+	// writeFlushThreshold is length of buffered frames to flush without waiting for the write queue to drain.
+	writeFlushThreshold = 1 << 15
+
+	defaultSettingsTimeout = 10 * time.Second
+
+	defaultKeepaliveTimeout = 15 * time.Second
+
+	defaultHandshakeTimeout = 10 * time.Second
+
+	defaultPrefaceTimeout = 10 * time.Second
+
+	// shutdownPollInterval is interval to check whether connections are closed while shutting down.
+	shutdownPollInterval = 100 * time.Millisecond
+
+	// closeWriteTimeout is time to write frames queued before reader stops, such as the final GOAWAY.
+	// Writer can't block longer than it even if peer stops reading.
+	closeWriteTimeout = 5 * time.Second
 )
 
 var (
 	expectedClientPreface = []byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n")
+
+	// ErrServerClosed is returned by ListenAndServe after Shutdown or Close is called.
+	ErrServerClosed = errors.New("h2server: server closed")
 )
 
 func NewServer(config *ServerConfig) *Server {
@@ -43,12 +152,61 @@ func NewServer(config *ServerConfig) *Server {
 		logger = NullLogger()
 	}
 
+	padding := config.Padding
+	if padding == nil {
+		padding = NoPadding()
+	}
+
+	settingsTimeout := config.SettingsTimeout
+	if settingsTimeout == 0 {
+		settingsTimeout = defaultSettingsTimeout
+	}
+
+	keepaliveTimeout := config.KeepaliveTimeout
+	if keepaliveTimeout == 0 {
+		keepaliveTimeout = defaultKeepaliveTimeout
+	}
+
+	handshakeTimeout := config.HandshakeTimeout
+	if handshakeTimeout == 0 {
+		handshakeTimeout = defaultHandshakeTimeout
+	}
+
+	prefaceTimeout := config.PrefaceTimeout
+	if prefaceTimeout == 0 {
+		prefaceTimeout = defaultPrefaceTimeout
+	}
+
+	handler := config.Handler
+	if handler == nil && config.HTTPHandler != nil {
+		handler = FromHTTPHandler(config.HTTPHandler)
+	}
+
+	mp := config.Multiplexer
+	if mp == nil {
+		mp = DefaultMultiplexer(logger, handler)
+	}
+
 	return &Server{
-		logger:  config.Logger,
-		cert:    config.Certificate,
-		addr:    config.Address,
-		preface: config.Preface,
-		mp:      config.Multiplexer,
+		logger:            logger,
+		cert:              config.Certificate,
+		addr:              config.Address,
+		preface:           config.Preface,
+		mp:                mp,
+		registry:          config.FrameRegistry,
+		altSvc:            config.AltSvc,
+		origins:           config.Origins,
+		padding:           padding,
+		settingsTimeout:   settingsTimeout,
+		keepaliveInterval: config.KeepaliveInterval,
+		keepaliveTimeout:  keepaliveTimeout,
+		handshakeTimeout:  handshakeTimeout,
+		prefaceTimeout:    prefaceTimeout,
+		idleTimeout:       config.IdleTimeout,
+		maxConnectionAge:  config.MaxConnectionAge,
+		connState:         config.ConnState,
+		conns:             make(map[net.Conn]struct{}),
+		shutdown:          make(chan struct{}),
 	}
 }
 
@@ -63,14 +221,29 @@ func (sv *Server) ListenAndServe() error {
 	}
 	defer ln.Close()
 
+	if !sv.trackListener(ln) {
+		return ErrServerClosed
+	}
+
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
+			if sv.isShuttingDown() {
+				return ErrServerClosed
+			}
+
 			sv.logger.Write(ErrorLog, "failed to accept connection: %s\n", err.Error())
 			continue
 		}
 
+		if !sv.trackConn(conn) {
+			conn.Close()
+			continue
+		}
+		sv.setConnState(conn, ConnNew)
+
 		go func() {
+			defer sv.untrackConn(conn)
 			if err := sv.handleConn(conn.(*tls.Conn)); err != nil {
 				sv.connLog(conn, ErrorLog, err.Error())
 			}
@@ -78,14 +251,131 @@ func (sv *Server) ListenAndServe() error {
 	}
 }
 
+// Shutdown stops accepting connections and shuts down connections gracefully by GOAWAY.
+// Streams in flight are processed until the context is done, and then connections are closed by Close.
+func (sv *Server) Shutdown(ctx context.Context) error {
+	err := sv.stopListening()
+
+	sv.mu.Lock()
+	for conn := range sv.conns {
+		conn.SetReadDeadline(time.Now()) // interrupt reader to start shutdown
+	}
+	sv.mu.Unlock()
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if sv.numConns() == 0 {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			sv.Close()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close stops accepting connections and closes all connections immediately.
+func (sv *Server) Close() error {
+	err := sv.stopListening()
+
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+
+	for conn := range sv.conns {
+		conn.Close()
+	}
+	return err
+}
+
+// stopListening starts shutdown and closes listener.
+func (sv *Server) stopListening() error {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+
+	if sv.isShuttingDown() {
+		return nil
+	}
+	close(sv.shutdown)
+
+	if sv.listener == nil {
+		return nil
+	}
+	return sv.listener.Close()
+}
+
+func (sv *Server) isShuttingDown() bool {
+	select {
+	case <-sv.shutdown:
+		return true
+	default:
+		return false
+	}
+}
+
+func (sv *Server) trackListener(ln net.Listener) bool {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+
+	if sv.isShuttingDown() {
+		return false
+	}
+
+	sv.listener = ln
+	return true
+}
+
+func (sv *Server) trackConn(conn net.Conn) bool {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+
+	if sv.isShuttingDown() {
+		return false
+	}
+
+	sv.conns[conn] = struct{}{}
+	return true
+}
+
+func (sv *Server) untrackConn(conn net.Conn) {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	delete(sv.conns, conn)
+}
+
+func (sv *Server) numConns() int {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	return len(sv.conns)
+}
+
+// Stats returns statistics of the server so far.
+func (sv *Server) Stats() ServerStats {
+	return ServerStats{
+		RefusedStreams: atomic.LoadUint64(&sv.stats.RefusedStreams),
+		Writes: WriteStats{
+			Frames:  atomic.LoadUint64(&sv.stats.Writes.Frames),
+			Octets:  atomic.LoadUint64(&sv.stats.Writes.Octets),
+			Flushes: atomic.LoadUint64(&sv.stats.Writes.Flushes),
+		},
+	}
+}
+
 func (sv *Server) handleConn(conn *tls.Conn) error {
 	defer func() {
 		conn.Close()
+		sv.setConnState(conn, ConnClosed)
 	}()
 
 	sv.connLog(conn, DebugLog, "connected")
+	sv.setConnState(conn, ConnHandshaking)
 
 	// Handshake
+	conn.SetDeadline(time.Now().Add(sv.handshakeTimeout))
 	if err := conn.Handshake(); err != nil {
 		return fmt.Errorf("failed to handshake: %w", err)
 	}
@@ -98,16 +388,45 @@ func (sv *Server) handleConn(conn *tls.Conn) error {
 	sv.connLog(conn, DebugLog, "handshake completed")
 
 	// Exchange server and client preface
+	conn.SetDeadline(time.Now().Add(sv.prefaceTimeout))
+	framer := NewFramer(conn)
+	framer.SetFrameRegistry(sv.registry)
+	framer.SetPooled(true)
+
 	preface, err := sv.preface().Build()
 	if err != nil {
 		return fmt.Errorf("failed to generate server preface: %w", err)
 	}
-	if _, err := preface.WriteTo(conn); err != nil {
+
+	for _, param := range preface.(*SettingsFrame).Params() {
+		if param.ID == MaxFrameSizeSetting {
+			if err := framer.SetMaxReadFrameSize(param.Value); err != nil {
+				return fmt.Errorf("failed to generate server preface: %w", err)
+			}
+		}
+	}
+
+	if err := framer.WriteFrame(preface); err != nil {
 		return err
 	}
 
+	if err := sv.advertiseAltSvc(framer); err != nil {
+		return err
+	}
+
+	if err := sv.advertiseOrigins(framer); err != nil {
+		return err
+	}
+
+	if err := framer.Flush(); err != nil {
+		return err
+	}
+
+	settings := newConnSettings()
+	settings.sent(preface.(*SettingsFrame), time.Now().Add(sv.settingsTimeout))
+
 	clientPreface := make([]byte, len(expectedClientPreface))
-	if _, err := conn.Read(clientPreface); err != nil {
+	if _, err := io.ReadFull(conn, clientPreface); err != nil {
 		return err
 	}
 
@@ -116,43 +435,137 @@ func (sv *Server) handleConn(conn *tls.Conn) error {
 	}
 
 	sv.connLog(conn, DebugLog, "accept client preface")
+	conn.SetDeadline(time.Time{})
 
 	// Start reader and writer
-	pseudoConn := newPseudoConn(conn)
+	pseudoConn := newPseudoConn(conn, framer, sv.padding, settings, sv.settingsTimeout)
 	ctx, cancel := context.WithCancel(contextWithConn(context.Background(), pseudoConn))
 
 	var rErr, wErr error
-	cancelOnce := &sync.Once{}
 	wg := &sync.WaitGroup{}
 	wg.Add(2)
 
 	go func() {
-		wErr = sv.runWriter(ctx, conn)
-		cancelOnce.Do(cancel)
+		wErr = sv.runWriter(ctx, framer)
+		cancel()
+		conn.SetReadDeadline(time.Now()) // interrupt reader
 		wg.Done()
 	}()
 
 	mp := sv.mp(pseudoConn)
 
 	go func() {
-		rErr = sv.runReader(ctx, conn, mp)
-		cancelOnce.Do(cancel)
+		rErr = sv.runReader(ctx, conn, framer, mp)
+		pseudoConn.Close() // writer exits after sending all queued frames
+		conn.SetWriteDeadline(time.Now().Add(closeWriteTimeout))
 		wg.Done()
 	}()
 
 	wg.Wait()
 
-	// TODO: Handle rErr and wErr
-	mp.Terminated()
+	if rErr != nil {
+		sv.connLog(conn, DebugLog, "reader stopped: %s", rErr.Error())
+	}
+	if wErr != nil {
+		sv.connLog(conn, DebugLog, "writer stopped: %s", wErr.Error())
+	}
+
+	stats := pseudoConn.WriteStats()
+	atomic.AddUint64(&sv.stats.Writes.Frames, stats.Frames)
+	atomic.AddUint64(&sv.stats.Writes.Octets, stats.Octets)
+	atomic.AddUint64(&sv.stats.Writes.Flushes, stats.Flushes)
+	sv.connLog(conn, DebugLog, "wrote %d frames(%d octets) by %d flushes", stats.Frames, stats.Octets, stats.Flushes)
+	mp.Terminated(newTermination(rErr, wErr, sv.isShuttingDown()))
+
+	return nil
+}
+
+func (sv *Server) advertiseAltSvc(framer *Framer) error {
+	if sv.altSvc == nil {
+		return nil
+	}
+
+	for _, builder := range sv.altSvc() {
+		altSvc, err := builder.Build()
+		if err != nil {
+			return fmt.Errorf("failed to generate alt svc frame: %w", err)
+		}
+
+		if altSvc.StreamID() != 0x00 {
+			return fmt.Errorf("alt svc frame sent after server preface must be on stream ID(0)")
+		}
+
+		if err := framer.WriteFrame(altSvc); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
+// advertiseOrigins sends origins by ORIGIN frames.
+// Origins are split into multiple frames, so that each frame doesn't exceed max frame size.
+func (sv *Server) advertiseOrigins(framer *Framer) error {
+	maxFrameSize := int(framer.MaxWriteFrameSize())
+
+	for origins := sv.origins; len(origins) > 0; {
+		// Each origin is prefixed by its length(2 octets).
+		n, size := 0, 0
+		for ; n < len(origins) && size+2+len(origins[n]) <= maxFrameSize; n++ {
+			size += 2 + len(origins[n])
+		}
+
+		if n == 0 {
+			return fmt.Errorf("origin(%d octets) exceeds max frame size(%d)", len(origins[0]), maxFrameSize)
+		}
+
+		origin, err := NewOriginFrameBuilder().Add(origins[:n]...).Build()
+		if err != nil {
+			return fmt.Errorf("failed to generate origin frame: %w", err)
+		}
+
+		if err := framer.WriteFrame(origin); err != nil {
+			return err
+		}
+		origins = origins[n:]
+	}
+
+	return nil
+}
+
+func (sv *Server) setConnState(conn net.Conn, state ConnState) {
+	if sv.connState != nil {
+		sv.connState(conn, state)
+	}
+}
+
 func (sv *Server) connLog(conn net.Conn, level LogLevel, format string, args ...interface{}) {
 	sv.logger.Write(level, fmt.Sprintf("<%s> ", conn.RemoteAddr())+format, args...)
 }
 
-func (sv *Server) runReader(ctx context.Context, conn net.Conn, mp Multiplexer) error {
+// runReader reads and verifies frames, and passes them to Multiplexer.
+// If the frame has stream error, the stream is reset by RST_STREAM.
+// If connection error occurs, runReader sends GOAWAY and returns the error.
+// While the server is shutting down, it returns nil when all streams are closed after the final GOAWAY.
+// If peer sends GOAWAY, it returns peerGoAwayError when all streams are closed.
+func (sv *Server) runReader(ctx context.Context, conn net.Conn, framer *Framer, mp Multiplexer) error {
+	pc := connFromContext(ctx)
+	ka := newKeepalive(sv.keepaliveInterval, sv.keepaliveTimeout, time.Now())
+	cl := newConnLifetime(sv.idleTimeout, sv.maxConnectionAge, time.Now())
+	gs := &gracefulShutdown{}
+	var lastStreamID uint32
+	var peerGoAway *peerGoAwayError
+
+	// Draining connection doesn't become active or idle again.
+	state := ConnIdle
+	sv.setConnState(conn, state)
+	transition := func(next ConnState) {
+		if state != next && state != ConnDraining {
+			state = next
+			sv.setConnState(conn, state)
+		}
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -160,32 +573,318 @@ func (sv *Server) runReader(ctx context.Context, conn net.Conn, mp Multiplexer)
 		default:
 		}
 
-		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
-		f, err := Read(conn)
+		now, activeStreams := time.Now(), mp.ActiveStreams()
+		if activeStreams == 0 {
+			if peerGoAway != nil {
+				return peerGoAway
+			}
+			if gs.finished {
+				return nil
+			}
+			transition(ConnIdle)
+		} else {
+			transition(ConnActive)
+		}
+
+		if ka.expired(now) {
+			return fmt.Errorf("keepalive ping isn't acknowledged in %s", sv.keepaliveTimeout)
+		}
+
+		cl.observe(activeStreams, now)
+		if !gs.started && cl.idle(activeStreams, now) {
+			return sv.goAway(ctx, pc, lastStreamID, NewConnectionError(NoError, "connection is idle for %s", sv.idleTimeout))
+		}
+
+		// Shutdown is checked after setting deadline, because Shutdown interrupts reader by setting deadline.
+		deadline := sv.readDeadline(pc, ka, cl, gs.started || peerGoAway != nil || sv.isShuttingDown())
+		conn.SetReadDeadline(deadline)
+
+		if sv.isShuttingDown() || cl.aged(now) {
+			frames, err := gs.start()
+			if err != nil {
+				return fmt.Errorf("failed to generate frames to shut down: %w", err)
+			}
+
+			for _, f := range frames {
+				if err := pc.Write(ctx, f); err != nil {
+					return err
+				}
+			}
+			transition(ConnDraining)
+		}
+
+		// SETTINGS sent by UpdateSettings is checked after setting deadline too, because it interrupts reader in the same way.
+		if ack, ok := pc.settings.ackDeadline(); ok && (deadline.IsZero() || ack.Before(deadline)) {
+			conn.SetReadDeadline(ack)
+		}
+
+		// Framer returns timeout as it is only if the frame can be resumed, so the error isn't unwrapped.
+		f, err := framer.ReadFrame()
 		if err != nil {
-			if opErr, ok := err.(*net.OpError); ok && opErr.Timeout() {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				now := time.Now()
+				if ackDeadline, ok := pc.settings.ackDeadline(); ok && !now.Before(ackDeadline) {
+					return sv.goAway(ctx, pc, lastStreamID, NewConnectionError(SettingsTimeoutError, "settings frame isn't acknowledged in %s", sv.settingsTimeout))
+				}
+
+				ping, err := ka.ping(now)
+				if err != nil {
+					return fmt.Errorf("failed to generate ping frame: %w", err)
+				}
+				if ping != nil {
+					pc.writePriority(ctx, ping)
+				}
 				continue
 			}
+
+			var extErr *ExtensionFrameError
+			if errors.As(err, &extErr) {
+				sv.connLog(conn, DebugLog, "ignore invalid extension frame: %s", err.Error())
+				continue
+			}
+
+			if IsH2Error(err) {
+				return sv.goAway(ctx, pc, lastStreamID, err)
+			}
 			return err
 		}
 
-		mp.Received(f)
+		// Errors of extension frames are treated as connection error only if it's ConnectionError explicitly.
+		if err := f.Verify(); err != nil {
+			var streamErr *StreamError
+			var connErr *ConnectionError
+
+			if f.Type().IsUnknown() && !errors.As(err, &streamErr) && !errors.As(err, &connErr) {
+				sv.connLog(conn, DebugLog, "ignore invalid extension frame: %s", err.Error())
+				continue
+			}
+
+			if err := sv.handleFrameError(ctx, conn, pc, mp, lastStreamID, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		ka.read(time.Now())
+
+		switch typed := f.(type) {
+		case *SettingsFrame:
+			if err := sv.handleSettings(ctx, pc, framer, typed); err != nil {
+				return sv.goAway(ctx, pc, lastStreamID, err)
+			}
+
+		case *PingFrame:
+			if err := sv.handlePing(ctx, pc, ka, typed); err != nil {
+				return sv.goAway(ctx, pc, lastStreamID, err)
+			}
+
+			goAway, err := gs.acknowledged(typed, lastStreamID)
+			if err != nil {
+				return fmt.Errorf("failed to generate go away frame: %w", err)
+			}
+
+			if goAway != nil {
+				if err := pc.Write(ctx, goAway); err != nil {
+					return err
+				}
+				mp.GoAway(lastStreamID)
+			}
+
+		case *GoAwayFrame:
+			// Debug data is copied because payload of the frame is valid only until next read.
+			peerGoAway = &peerGoAwayError{code: typed.ErrorCode(), debugData: append([]byte(nil), typed.DebugData()...)}
+			if typed.ErrorCode() != NoError {
+				return peerGoAway
+			}
+			transition(ConnDraining)
+		}
+
+		if f.Type() == HeadersFrameType && f.StreamID() > lastStreamID {
+			lastStreamID = f.StreamID()
+		}
+
+		if err := mp.Received(f); err != nil {
+			if err := sv.handleFrameError(ctx, conn, pc, mp, lastStreamID, err); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readDeadline returns the earliest deadline to check acknowledgement of SETTINGS, keepalive and lifetime of the connection.
+// While the connection is shutting down, reader wakes up periodically to check whether streams are closed.
+// It returns zero time if reader doesn't need to wake up.
+func (sv *Server) readDeadline(pc *pseudoConn, ka *keepalive, cl *connLifetime, draining bool) time.Time {
+	var deadline time.Time
+	earlier := func(t time.Time, ok bool) {
+		if ok && (deadline.IsZero() || t.Before(deadline)) {
+			deadline = t
+		}
+	}
+
+	earlier(pc.settings.ackDeadline())
+	earlier(ka.deadline())
+
+	if draining {
+		earlier(time.Now().Add(shutdownPollInterval), true)
+	} else {
+		earlier(cl.deadline())
 	}
+
+	return deadline
 }
 
-func (*Server) runWriter(ctx context.Context, conn net.Conn) error {
+// handleFrameError resets the stream if the error is StreamError and notifies Multiplexer of it.
+// Otherwise, it sends GOAWAY and returns the error as ConnectionError.
+func (sv *Server) handleFrameError(ctx context.Context, conn net.Conn, pc *pseudoConn, mp Multiplexer, lastStreamID uint32, err error) error {
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) {
+		return sv.goAway(ctx, pc, lastStreamID, err)
+	}
+
+	if streamErr.Code() == RefusedStreamError {
+		atomic.AddUint64(&sv.stats.RefusedStreams, 1)
+	}
+
+	sv.connLog(conn, DebugLog, "reset stream: %s", err.Error())
+	if err := sv.resetStream(ctx, pc, streamErr); err != nil {
+		return err
+	}
+
+	mp.Reset(streamErr.StreamID(), streamErr.Code())
+	return nil
+}
+
+// handleSettings applies our SETTINGS acknowledged by peer, or applies peer's SETTINGS and acknowledges it.
+// See: https://tools.ietf.org/html/rfc7540#section-6.5.3
+func (sv *Server) handleSettings(ctx context.Context, pc *pseudoConn, framer *Framer, settings *SettingsFrame) error {
+	if settings.IsACK() {
+		if !pc.settings.acknowledged() {
+			return NewConnectionError(ProtocolError, "received settings ack without unacknowledged settings")
+		}
+
+		// Peer may send frames up to our new max frame size after acknowledging it.
+		return framer.SetMaxReadFrameSize(pc.LocalSettings().MaxFrameSize)
+	}
+
+	if err := pc.settings.received(settings); err != nil {
+		return err
+	}
+
+	ack, err := NewSettingsFrameBuilder().ACK().Build()
+	if err != nil {
+		return fmt.Errorf("failed to generate settings frame: %w", err)
+	}
+
+	return pc.writeSettingsACK(ctx, framer, ack)
+}
+
+// handlePing acknowledges peer's PING ahead of other frames, or measures round trip time by ACK of our PING.
+// See: https://tools.ietf.org/html/rfc7540#section-6.7
+func (sv *Server) handlePing(ctx context.Context, pc *pseudoConn, ka *keepalive, ping *PingFrame) error {
+	if ping.IsACK() {
+		if rtt, ok := ka.acknowledged(ping, time.Now()); ok {
+			pc.updateRTT(rtt)
+		}
+		return nil
+	}
+
+	ack, err := ping.ACK()
+	if err != nil {
+		return fmt.Errorf("failed to generate ping frame: %w", err)
+	}
+
+	pc.writePriority(ctx, ack)
+	return nil
+}
+
+func (sv *Server) resetStream(ctx context.Context, pc *pseudoConn, streamErr *StreamError) error {
+	rst, err := NewRstStreamFrameBuilder(streamErr.StreamID(), streamErr.Code()).Build()
+	if err != nil {
+		return fmt.Errorf("failed to generate rst stream frame: %w", err)
+	}
+
+	return pc.Write(ctx, rst)
+}
+
+// goAway sends GOAWAY for connection error and returns the error as ConnectionError.
+func (sv *Server) goAway(ctx context.Context, pc *pseudoConn, lastStreamID uint32, err error) error {
+	connErr := AsConnectionError(err)
+
+	goAway, buildErr := NewGoAwayFrameBuilder(lastStreamID, connErr.Code()).DebugData([]byte(connErr.Error())).Build()
+	if buildErr != nil {
+		return fmt.Errorf("failed to generate go away frame: %w", buildErr)
+	}
+
+	// The connection is closed even if GOAWAY can't be written.
+	pc.Write(ctx, goAway)
+	return connErr
+}
+
+// runWriter writes frames queued to connection.
+// Queued frames are coalesced into write buffer and flushed when the queue drains or buffer exceeds threshold.
+// Frames queued by writePriority are written ahead of other frames.
+// After the connection is closed, it returns when queued frames are written.
+func (*Server) runWriter(ctx context.Context, framer *Framer) error {
+	pc := connFromContext(ctx)
+	defer pc.stopped()
+
+	var waiting []chan struct{}
+	flush := func() error {
+		if err := framer.Flush(); err != nil {
+			return fmt.Errorf("failed to send frame: %w", err)
+		}
+
+		for _, flushed := range waiting {
+			close(flushed)
+		}
+		waiting = waiting[:0]
+		return nil
+	}
+
 	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		case outgoing, ok := <-connFromContext(ctx).writeCh():
-			if !ok {
+		o := pc.next()
+		if o == nil {
+			select {
+			case <-ctx.Done():
 				return nil
+			case f := <-pc.priority:
+				o = &outgoing{frames: []Frame{f}}
+			case o = <-pc.w:
+			case <-pc.closed:
+				if o = pc.next(); o == nil {
+					return nil
+				}
+			}
+		}
+
+		for ; o != nil; o = pc.next() {
+			if o.flushed != nil {
+				waiting = append(waiting, o.flushed)
+				continue
+			}
+
+			for _, f := range o.frames {
+				if err := framer.WriteFrame(f); err != nil {
+					return fmt.Errorf("failed to send frame: %w", err)
+				}
+			}
+
+			if o.settingsACK {
+				if err := pc.settingsACKWritten(framer); err != nil {
+					return err
+				}
 			}
 
-			if _, err := outgoing.WriteTo(conn); err != nil {
-				return fmt.Errorf("failed to send frame: %w", err)
+			if framer.Buffered() >= writeFlushThreshold {
+				if err := flush(); err != nil {
+					return err
+				}
 			}
 		}
+
+		if err := flush(); err != nil {
+			return err
+		}
 	}
 }
@@ -0,0 +1,872 @@
+package h2server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testReader struct {
+	sv     *Server
+	client net.Conn
+	framer *Framer
+	pc     *pseudoConn
+	errCh  chan error
+}
+
+// startTestReader runs runReader on in-memory connection with settings of the connection.
+func startTestReader(t *testing.T, config *ServerConfig, settings *connSettings) *testReader {
+	t.Helper()
+
+	server, client := net.Pipe()
+	sv := NewServer(config)
+	framer := NewFramer(server)
+	framer.SetFrameRegistry(sv.registry)
+	pc := newPseudoConn(server, framer, sv.padding, settings, sv.settingsTimeout)
+	ctx, cancel := context.WithCancel(contextWithConn(context.Background(), pc))
+
+	tr := &testReader{sv: sv, client: client, framer: framer, pc: pc, errCh: make(chan error, 1)}
+	go func() {
+		tr.errCh <- sv.runReader(ctx, server, framer, DefaultMultiplexer(NullLogger(), waitingHandler())(pc))
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		client.Close()
+		server.Close()
+	})
+	return tr
+}
+
+func (tr *testReader) send(t *testing.T, f Frame) {
+	t.Helper()
+
+	if _, err := tr.client.Write(appendFrame(nil, f)); err != nil {
+		t.Fatalf("failed to send frame: %v", err)
+	}
+}
+
+func (tr *testReader) written(t *testing.T) Frame {
+	t.Helper()
+
+	select {
+	case f := <-tr.pc.priority:
+		return f
+	case o := <-tr.pc.w:
+		return o.frames[0]
+	case <-time.After(time.Second):
+		t.Fatalf("no frame is written")
+		return nil
+	}
+}
+
+func (tr *testReader) stopped(t *testing.T) error {
+	t.Helper()
+
+	select {
+	case err := <-tr.errCh:
+		return err
+	case <-time.After(time.Second):
+		t.Fatalf("reader isn't stopped")
+		return nil
+	}
+}
+
+func TestServer_advertiseOrigins(t *testing.T) {
+	// 2000 origins of 20 octets don't fit in a frame.
+	var origins []string
+	for i := 0; i < 2000; i++ {
+		origins = append(origins, fmt.Sprintf("https://%08d.com", i))
+	}
+
+	tests := []struct {
+		name    string
+		origins []string
+		frames  int
+		valid   bool
+	}{
+		{name: "no origin", valid: true},
+		{name: "origins in a frame", origins: origins[:10], frames: 1, valid: true},
+		{name: "origins split into frames", origins: origins, frames: 3, valid: true},
+		{name: "origin exceeding max frame size", origins: []string{"https://" + strings.Repeat("a", defaultMaxFrameSize) + ".com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			framer := NewFramer(readWriter{Writer: buf})
+
+			err := NewServer(&ServerConfig{Origins: tt.origins}).advertiseOrigins(framer)
+			if tt.valid != (err == nil) {
+				t.Fatalf("advertiseOrigins() got error = %v", err)
+			}
+
+			if err := framer.Flush(); err != nil {
+				t.Fatalf("Flush() got error = %v", err)
+			}
+
+			var got []string
+			frames := 0
+			for buf.Len() > 0 {
+				f, err := Read(buf)
+				if err != nil {
+					t.Fatalf("failed to read frame: %v", err)
+				}
+
+				if len(f.Payload()) > defaultMaxFrameSize {
+					t.Errorf("origin frame(%d octets) exceeds max frame size", len(f.Payload()))
+				}
+
+				decoded, err := DecodeOriginFrame(f.(*UnknownFrame))
+				if err != nil {
+					t.Fatalf("failed to decode origin frame: %v", err)
+				}
+				got = append(got, decoded.(*OriginFrame).Origins()...)
+				frames++
+			}
+
+			if !tt.valid {
+				return
+			}
+
+			if frames != tt.frames || !reflect.DeepEqual(got, tt.origins) {
+				t.Errorf("advertiseOrigins() sent %d frames with %d origins, want = %d frames with %d origins", frames, len(got), tt.frames, len(tt.origins))
+			}
+		})
+	}
+}
+
+func TestServer_runReader_Settings(t *testing.T) {
+	tr := startTestReader(t, &ServerConfig{}, newConnSettings())
+
+	tr.send(t, buildSettingsFrame(t,
+		&SettingsFrameParam{ID: InitialWindowSizeSetting, Value: 1 << 20},
+		&SettingsFrameParam{ID: MaxFrameSizeSetting, Value: 1 << 15},
+	))
+
+	ack, ok := tr.written(t).(*SettingsFrame)
+	if !ok || !ack.IsACK() {
+		t.Fatalf("settings frame isn't acknowledged: %+v", ack)
+	}
+
+	if got := tr.pc.PeerSettings().InitialWindowSize; got != 1<<20 {
+		t.Errorf("PeerSettings().InitialWindowSize got = %d, want = %d", got, 1<<20)
+	}
+
+	if got := tr.framer.MaxWriteFrameSize(); got != 1<<15 {
+		t.Errorf("MaxWriteFrameSize() got = %d, want = %d", got, 1<<15)
+	}
+}
+
+func TestServer_runReader_InvalidSettings(t *testing.T) {
+	tr := startTestReader(t, &ServerConfig{}, newConnSettings())
+
+	tr.send(t, &SettingsFrame{frame: &frame{
+		typ:     SettingsFrameType,
+		payload: []byte{0x00, 0x02, 0x00, 0x00, 0x00, 0x02}, // ENABLE_PUSH = 2
+	}})
+
+	goAway, ok := tr.written(t).(*GoAwayFrame)
+	if !ok || goAway.ErrorCode() != ProtocolError {
+		t.Fatalf("invalid settings don't cause go away: %+v", goAway)
+	}
+
+	if got := UnwrapErrorCode(tr.stopped(t)); got != ProtocolError {
+		t.Errorf("runReader() got = %s, want = %s", got, ProtocolError)
+	}
+}
+
+func TestServer_runReader_SettingsAck(t *testing.T) {
+	settings := newConnSettings()
+	settings.sent(buildSettingsFrame(t, &SettingsFrameParam{ID: MaxConcurrentStreamsSetting, Value: 100}), time.Now().Add(time.Minute))
+	tr := startTestReader(t, &ServerConfig{}, settings)
+
+	ack, err := NewSettingsFrameBuilder().ACK().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.send(t, ack)
+
+	// Send another frame to wait for the ACK to be handled.
+	tr.send(t, buildSettingsFrame(t))
+	tr.written(t)
+
+	if got := tr.pc.LocalSettings().MaxConcurrentStreams; got != 100 {
+		t.Errorf("LocalSettings().MaxConcurrentStreams got = %d, want = %d", got, 100)
+	}
+
+	tr.send(t, ack)
+	goAway, ok := tr.written(t).(*GoAwayFrame)
+	if !ok || goAway.ErrorCode() != ProtocolError {
+		t.Fatalf("unexpected settings ack doesn't cause go away: %+v", goAway)
+	}
+}
+
+func TestServer_runReader_SettingsAck_MaxFrameSize(t *testing.T) {
+	settings := newConnSettings()
+	settings.sent(buildSettingsFrame(t, &SettingsFrameParam{ID: MaxFrameSizeSetting, Value: 1 << 20}), time.Now().Add(time.Minute))
+	tr := startTestReader(t, &ServerConfig{}, settings)
+
+	ack, err := NewSettingsFrameBuilder().ACK().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.send(t, ack)
+
+	// Frame exceeding the default max frame size is allowed after the ACK. Unknown frame is ignored.
+	// Reader that rejects the frame stops reading, so sending must not block the test forever.
+	tr.client.SetWriteDeadline(time.Now().Add(time.Second))
+	tr.send(t, &UnknownFrame{frame: &frame{typ: 0xF1, payload: make([]byte, 20000)}})
+
+	ping, err := NewPingFrameBuilder([8]byte{1, 2, 3, 4, 5, 6, 7, 8}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.send(t, ping)
+
+	if f, ok := tr.written(t).(*PingFrame); !ok || !f.IsACK() {
+		t.Fatalf("ping frame isn't acknowledged after large frame: %+v", f)
+	}
+
+	if got := tr.framer.MaxReadFrameSize(); got != 1<<20 {
+		t.Errorf("MaxReadFrameSize() got = %d, want = %d", got, 1<<20)
+	}
+}
+
+func TestServer_runReader_SettingsTimeout(t *testing.T) {
+	timeout := 50 * time.Millisecond
+	settings := newConnSettings()
+	settings.sent(buildSettingsFrame(t), time.Now().Add(timeout))
+	tr := startTestReader(t, &ServerConfig{SettingsTimeout: timeout}, settings)
+
+	goAway, ok := tr.written(t).(*GoAwayFrame)
+	if !ok || goAway.ErrorCode() != SettingsTimeoutError {
+		t.Fatalf("settings timeout doesn't cause go away: %+v", goAway)
+	}
+
+	if got := UnwrapErrorCode(tr.stopped(t)); got != SettingsTimeoutError {
+		t.Errorf("runReader() got = %s, want = %s", got, SettingsTimeoutError)
+	}
+}
+
+func TestServer_runReader_UpdateSettingsTimeout(t *testing.T) {
+	tr := startTestReader(t, &ServerConfig{SettingsTimeout: 50 * time.Millisecond}, newConnSettings())
+
+	// Reader is blocked without deadline until SETTINGS is sent at runtime.
+	time.Sleep(10 * time.Millisecond)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- tr.pc.UpdateSettings(context.Background(), NewSettingsFrameBuilder().Add(&SettingsFrameParam{ID: MaxConcurrentStreamsSetting, Value: 1}))
+	}()
+
+	if settings, ok := tr.written(t).(*SettingsFrame); !ok || settings.IsACK() {
+		t.Fatalf("updated settings aren't written: %+v", settings)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("UpdateSettings() got error = %v", err)
+	}
+
+	goAway, ok := tr.written(t).(*GoAwayFrame)
+	if !ok || goAway.ErrorCode() != SettingsTimeoutError {
+		t.Fatalf("settings timeout doesn't cause go away: %+v", goAway)
+	}
+
+	if got := UnwrapErrorCode(tr.stopped(t)); got != SettingsTimeoutError {
+		t.Errorf("runReader() got = %s, want = %s", got, SettingsTimeoutError)
+	}
+}
+
+func TestServer_runReader_MaxConcurrentStreams(t *testing.T) {
+	tr := startTestReader(t, &ServerConfig{}, newConnSettings())
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- tr.pc.UpdateSettings(context.Background(), NewSettingsFrameBuilder().Add(&SettingsFrameParam{ID: MaxConcurrentStreamsSetting, Value: 1}))
+	}()
+
+	if settings, ok := tr.written(t).(*SettingsFrame); !ok || settings.IsACK() {
+		t.Fatalf("updated settings aren't written: %+v", settings)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("UpdateSettings() got error = %v", err)
+	}
+
+	ack, err := NewSettingsFrameBuilder().ACK().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.send(t, ack)
+
+	for _, id := range []uint32{1, 3} {
+		frames, err := NewHeadersFrameBuilder(id, testRequestBlock).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr.send(t, frames[0])
+	}
+
+	rst, ok := tr.written(t).(*RstStreamFrame)
+	if !ok || rst.StreamID() != 3 || rst.ErrorCode() != RefusedStreamError {
+		t.Fatalf("stream beyond the limit isn't refused: %+v", rst)
+	}
+
+	if got := tr.sv.Stats().RefusedStreams; got != 1 {
+		t.Errorf("Stats().RefusedStreams got = %d, want = %d", got, 1)
+	}
+}
+
+func TestServer_runReader_Ping(t *testing.T) {
+	tr := startTestReader(t, &ServerConfig{}, newConnSettings())
+
+	ping, err := NewPingFrameBuilder([8]byte{1, 2, 3, 4, 5, 6, 7, 8}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.send(t, ping)
+
+	select {
+	case f := <-tr.pc.priority:
+		ack, ok := f.(*PingFrame)
+		if !ok || !ack.IsACK() || ack.Data() != [8]byte{1, 2, 3, 4, 5, 6, 7, 8} {
+			t.Errorf("ping frame isn't acknowledged: %+v", f)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("ping frame isn't acknowledged")
+	}
+}
+
+func TestServer_runReader_Keepalive(t *testing.T) {
+	tr := startTestReader(t, &ServerConfig{KeepaliveInterval: 20 * time.Millisecond, KeepaliveTimeout: time.Second}, newConnSettings())
+
+	for i := 0; i < 2; i++ {
+		ping, ok := tr.written(t).(*PingFrame)
+		if !ok || ping.IsACK() {
+			t.Fatalf("keepalive ping isn't sent: %+v", ping)
+		}
+
+		ack, err := ping.ACK()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr.send(t, ack)
+	}
+
+	// Wait for the next PING to make sure that the last ACK is handled.
+	tr.written(t)
+
+	if tr.pc.RTT() <= 0 {
+		t.Errorf("RTT() got = %s, want positive", tr.pc.RTT())
+	}
+}
+
+func TestServer_runReader_PartialFrame(t *testing.T) {
+	tr := startTestReader(t, &ServerConfig{KeepaliveInterval: 20 * time.Millisecond, KeepaliveTimeout: time.Second}, newConnSettings())
+
+	ping, err := NewPingFrameBuilder([8]byte{1, 2, 3, 4, 5, 6, 7, 8}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Keepalive deadline expires while the frame is partially sent.
+	encoded := appendFrame(nil, ping)
+	if _, err := tr.client.Write(encoded[:12]); err != nil {
+		t.Fatalf("failed to send frame: %v", err)
+	}
+
+	if _, ok := tr.written(t).(*PingFrame); !ok {
+		t.Fatalf("keepalive ping isn't sent")
+	}
+
+	if _, err := tr.client.Write(encoded[12:]); err != nil {
+		t.Fatalf("failed to send frame: %v", err)
+	}
+
+	for {
+		select {
+		case err := <-tr.errCh:
+			t.Fatalf("runReader() got error = %v", err)
+		default:
+		}
+
+		if ack, ok := tr.written(t).(*PingFrame); ok && ack.IsACK() && ack.Data() == [8]byte{1, 2, 3, 4, 5, 6, 7, 8} {
+			return
+		}
+	}
+}
+
+func TestServer_runReader_InvalidExtensionFrame(t *testing.T) {
+	reg := NewFrameRegistry()
+	err := reg.Register(0xF0, func(unknown *UnknownFrame) (IncomingFrame, error) {
+		return nil, NewH2Error(FrameSizeError, "invalid payload")
+	})
+	if err != nil {
+		t.Fatalf("Register() got error = %v", err)
+	}
+
+	tr := startTestReader(t, &ServerConfig{FrameRegistry: reg}, newConnSettings())
+
+	// Extension frame failed to decode is ignored, and following frames are handled.
+	if _, err := tr.client.Write([]byte{0x00, 0x00, 0x00, 0xF0, 0x00, 0x00, 0x00, 0x00, 0x00}); err != nil {
+		t.Fatalf("failed to send frame: %v", err)
+	}
+
+	ping, err := NewPingFrameBuilder([8]byte{1, 2, 3, 4, 5, 6, 7, 8}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.send(t, ping)
+
+	if ack, ok := tr.written(t).(*PingFrame); !ok || !ack.IsACK() {
+		t.Fatalf("ping frame isn't acknowledged: %+v", ack)
+	}
+
+	select {
+	case err := <-tr.errCh:
+		t.Errorf("runReader() stopped by invalid extension frame: %v", err)
+	default:
+	}
+}
+
+func TestServer_runReader_KeepaliveTimeout(t *testing.T) {
+	tr := startTestReader(t, &ServerConfig{KeepaliveInterval: 20 * time.Millisecond, KeepaliveTimeout: 20 * time.Millisecond}, newConnSettings())
+
+	if _, ok := tr.written(t).(*PingFrame); !ok {
+		t.Fatalf("keepalive ping isn't sent")
+	}
+
+	if err := tr.stopped(t); err == nil {
+		t.Errorf("runReader() doesn't return error when ping isn't acknowledged")
+	}
+}
+
+func TestServer_runReader_Shutdown(t *testing.T) {
+	tr := startTestReader(t, &ServerConfig{}, newConnSettings())
+
+	headers := func(t *testing.T, id uint32) Frame {
+		frames, err := NewHeadersFrameBuilder(id, testRequestBlock).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return frames[0]
+	}
+
+	tr.send(t, headers(t, 1))
+	tr.sv.stopListening()
+
+	// Send frame to wake up reader if it's waiting for frames.
+	windowUpdate, err := NewWindowUpdateFrameBuilder(0, 1).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go tr.client.Write(appendFrame(nil, windowUpdate))
+
+	goAway, ok := tr.written(t).(*GoAwayFrame)
+	if !ok || goAway.LastStreamID() != maxStreamID || goAway.ErrorCode() != NoError {
+		t.Fatalf("the first go away isn't sent: %+v", goAway)
+	}
+
+	ping, ok := tr.written(t).(*PingFrame)
+	if !ok || ping.IsACK() {
+		t.Fatalf("ping isn't sent after the first go away: %+v", ping)
+	}
+
+	// Stream initiated before PING ACK is processed.
+	tr.send(t, headers(t, 3))
+
+	ack, err := ping.ACK()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.send(t, ack)
+
+	goAway, ok = tr.written(t).(*GoAwayFrame)
+	if !ok || goAway.LastStreamID() != 3 || goAway.ErrorCode() != NoError {
+		t.Fatalf("the final go away isn't sent: %+v", goAway)
+	}
+
+	// Stream initiated after the final GOAWAY is ignored.
+	tr.send(t, headers(t, 5))
+
+	for _, id := range []uint32{1, 3} {
+		rst, err := NewRstStreamFrameBuilder(id, CancelError).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr.send(t, rst)
+	}
+
+	if err := tr.stopped(t); err != nil {
+		t.Errorf("runReader() got error = %v after streams are closed", err)
+	}
+}
+
+func TestServer_runReader_IdleTimeout(t *testing.T) {
+	tr := startTestReader(t, &ServerConfig{IdleTimeout: 50 * time.Millisecond}, newConnSettings())
+
+	goAway, ok := tr.written(t).(*GoAwayFrame)
+	if !ok || goAway.ErrorCode() != NoError {
+		t.Fatalf("idle connection isn't closed by go away: %+v", goAway)
+	}
+
+	if got := UnwrapErrorCode(tr.stopped(t)); got != NoError {
+		t.Errorf("runReader() got = %s, want = %s", got, NoError)
+	}
+}
+
+func TestServer_runReader_MaxConnectionAge(t *testing.T) {
+	tr := startTestReader(t, &ServerConfig{MaxConnectionAge: 100 * time.Millisecond}, newConnSettings())
+
+	frames, err := NewHeadersFrameBuilder(1, testRequestBlock).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.send(t, frames[0])
+
+	if goAway, ok := tr.written(t).(*GoAwayFrame); !ok || goAway.LastStreamID() != maxStreamID {
+		t.Fatalf("the first go away isn't sent: %+v", goAway)
+	}
+
+	ping, ok := tr.written(t).(*PingFrame)
+	if !ok {
+		t.Fatalf("ping isn't sent after the first go away: %+v", ping)
+	}
+
+	ack, err := ping.ACK()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.send(t, ack)
+
+	if goAway, ok := tr.written(t).(*GoAwayFrame); !ok || goAway.LastStreamID() != 1 || goAway.ErrorCode() != NoError {
+		t.Fatalf("the final go away isn't sent: %+v", goAway)
+	}
+
+	rst, err := NewRstStreamFrameBuilder(1, CancelError).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.send(t, rst)
+
+	if err := tr.stopped(t); err != nil {
+		t.Errorf("runReader() got error = %v after streams are drained", err)
+	}
+}
+
+func TestServer_runReader_ConnState(t *testing.T) {
+	var mu sync.Mutex
+	var states []ConnState
+
+	tr := startTestReader(t, &ServerConfig{ConnState: func(_ net.Conn, state ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+		states = append(states, state)
+	}}, newConnSettings())
+
+	frames, err := NewHeadersFrameBuilder(1, testRequestBlock).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.send(t, frames[0])
+
+	rst, err := NewRstStreamFrameBuilder(1, CancelError).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.send(t, rst)
+
+	goAway, err := NewGoAwayFrameBuilder(0, NoError).DebugData([]byte("bye")).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.send(t, goAway)
+
+	var peerErr *peerGoAwayError
+	if err := tr.stopped(t); !errors.As(err, &peerErr) || peerErr.code != NoError || string(peerErr.debugData) != "bye" {
+		t.Fatalf("runReader() got = %v, want = peer go away", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	want := []ConnState{ConnIdle, ConnActive, ConnIdle, ConnDraining}
+	if len(states) != len(want) {
+		t.Fatalf("states got = %v, want = %v", states, want)
+	}
+
+	for i := range want {
+		if states[i] != want[i] {
+			t.Errorf("states got = %v, want = %v", states, want)
+			break
+		}
+	}
+}
+
+func TestServer_runReader_PeerGoAwayError(t *testing.T) {
+	tr := startTestReader(t, &ServerConfig{}, newConnSettings())
+
+	frames, err := NewHeadersFrameBuilder(1, testRequestBlock).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.send(t, frames[0])
+
+	goAway, err := NewGoAwayFrameBuilder(0, EnhanceYourCalmError).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.send(t, goAway)
+
+	// Connection is terminated immediately even though stream(1) is active.
+	var peerErr *peerGoAwayError
+	if err := tr.stopped(t); !errors.As(err, &peerErr) || peerErr.code != EnhanceYourCalmError {
+		t.Errorf("runReader() got = %v, want = peer go away", err)
+	}
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	sv := NewServer(&ServerConfig{Address: "127.0.0.1:0"})
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- sv.ListenAndServe()
+	}()
+
+	if err := sv.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() got error = %v", err)
+	}
+
+	select {
+	case err := <-errCh:
+		if err != ErrServerClosed {
+			t.Errorf("ListenAndServe() got = %v, want = %v", err, ErrServerClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("ListenAndServe() doesn't return after shutdown")
+	}
+
+	if err := sv.ListenAndServe(); err != ErrServerClosed {
+		t.Errorf("ListenAndServe() got = %v after shutdown, want = %v", err, ErrServerClosed)
+	}
+}
+
+func TestServer_Shutdown_Timeout(t *testing.T) {
+	sv := NewServer(&ServerConfig{})
+
+	server, client := net.Pipe()
+	defer client.Close()
+
+	if !sv.trackConn(server) {
+		t.Fatalf("trackConn() got = false before shutdown")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := sv.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown() got = %v, want = %v", err, context.DeadlineExceeded)
+	}
+
+	if _, err := client.Write([]byte{0}); err == nil {
+		t.Errorf("connection isn't closed after shutdown timeout")
+	}
+
+	if sv.trackConn(client) {
+		t.Errorf("trackConn() got = true after shutdown")
+	}
+}
+
+func TestServer_runWriter_Priority(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+
+	framer := NewFramer(server)
+	pc := newPseudoConn(server, framer, NoPadding(), newConnSettings(), defaultSettingsTimeout)
+	ctx := contextWithConn(context.Background(), pc)
+
+	data, err := NewDataFrameBuilder(1, []byte("data")).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ping, err := NewPingFrameBuilder([8]byte{}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ack, err := ping.(*PingFrame).ACK()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Queue DATA and PING ACK before the writer starts.
+	if err := pc.Write(ctx, data); err != nil {
+		t.Fatal(err)
+	}
+	pc.writePriority(ctx, ack)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- NewServer(&ServerConfig{}).runWriter(ctx, framer)
+	}()
+
+	for _, want := range []FrameType{PingFrameType, DataFrameType} {
+		f, err := Read(client)
+		if err != nil {
+			t.Fatalf("failed to read frame: %v", err)
+		}
+
+		if f.Type() != want {
+			t.Errorf("written frame type got = 0x%X, want = 0x%X", f.Type(), want)
+		}
+	}
+
+	pc.Close()
+	if err := <-errCh; err != nil {
+		t.Errorf("runWriter() got error = %v", err)
+	}
+}
+
+func TestServer_runWriter_SettingsACK(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+
+	settings := newConnSettings()
+	framer := NewFramer(server)
+	pc := newPseudoConn(server, framer, NoPadding(), settings, defaultSettingsTimeout)
+	ctx := contextWithConn(context.Background(), pc)
+
+	ack, err := NewSettingsFrameBuilder().ACK().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Larger max frame size applies before the ACK is written.
+	if err := settings.received(buildSettingsFrame(t, &SettingsFrameParam{ID: MaxFrameSizeSetting, Value: 1 << 15})); err != nil {
+		t.Fatal(err)
+	}
+	if err := pc.writeSettingsACK(ctx, framer, ack); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := framer.MaxWriteFrameSize(); got != 1<<15 {
+		t.Errorf("MaxWriteFrameSize() got = %d, want = %d", got, 1<<15)
+	}
+
+	// Frame built with larger size is queued before peer lowers max frame size.
+	data, err := NewDataFrameBuilder(1, make([]byte, 20000)).MaxFrameSize(1 << 15).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pc.Write(ctx, data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := settings.received(buildSettingsFrame(t, &SettingsFrameParam{ID: MaxFrameSizeSetting, Value: 1 << 14})); err != nil {
+		t.Fatal(err)
+	}
+	if err := pc.writeSettingsACK(ctx, framer, ack); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := framer.MaxWriteFrameSize(); got != 1<<15 {
+		t.Errorf("MaxWriteFrameSize() got = %d before the ACK is written, want = %d", got, 1<<15)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- NewServer(&ServerConfig{}).runWriter(ctx, framer)
+	}()
+
+	for _, want := range []FrameType{SettingsFrameType, DataFrameType, SettingsFrameType} {
+		f, err := Read(client)
+		if err != nil {
+			t.Fatalf("failed to read frame: %v", err)
+		}
+
+		if f.Type() != want {
+			t.Errorf("written frame type got = 0x%X, want = 0x%X", f.Type(), want)
+		}
+	}
+
+	pc.Close()
+	if err := <-errCh; err != nil {
+		t.Errorf("runWriter() got error = %v", err)
+	}
+
+	if got := framer.MaxWriteFrameSize(); got != 1<<14 {
+		t.Errorf("MaxWriteFrameSize() got = %d after the ACK is written, want = %d", got, 1<<14)
+	}
+}
+
+func TestServer_runWriter_Flush(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+
+	framer := NewFramer(server)
+	pc := newPseudoConn(server, framer, NoPadding(), newConnSettings(), defaultSettingsTimeout)
+	ctx := contextWithConn(context.Background(), pc)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- NewServer(&ServerConfig{}).runWriter(ctx, framer)
+	}()
+
+	data, err := NewDataFrameBuilder(1, []byte("data")).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pc.Write(ctx, data); err != nil {
+		t.Fatalf("Write() got error = %v", err)
+	}
+
+	flushed := make(chan error, 1)
+	go func() {
+		flushed <- pc.Flush(ctx)
+	}()
+
+	select {
+	case err := <-flushed:
+		t.Fatalf("Flush() returns before the frame is read: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if _, err := Read(client); err != nil {
+		t.Fatalf("failed to read frame: %v", err)
+	}
+
+	if err := <-flushed; err != nil {
+		t.Errorf("Flush() got error = %v", err)
+	}
+
+	// Frames queued before Close are written.
+	if err := pc.Write(ctx, data); err != nil {
+		t.Fatalf("Write() got error = %v", err)
+	}
+	pc.Close()
+
+	if _, err := Read(client); err != nil {
+		t.Fatalf("failed to read frame queued before close: %v", err)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Errorf("runWriter() got error = %v", err)
+	}
+
+	if err := pc.Flush(ctx); err != ErrConnClosed {
+		t.Errorf("Flush() got = %v after close, want = %v", err, ErrConnClosed)
+	}
+	want := WriteStats{Frames: 2, Octets: 2 * (9 + 4), Flushes: 2}
+	if got := pc.WriteStats(); got != want {
+		t.Errorf("WriteStats() got = %+v, want = %+v", got, want)
+	}
+}
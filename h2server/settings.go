@@ -0,0 +1,138 @@
+package h2server
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type (
+	// Settings is set of SETTINGS parameters in effect for an endpoint.
+	// See: https://tools.ietf.org/html/rfc7540#section-6.5.2
+	Settings struct {
+		HeaderTableSize      uint32
+		EnablePush           bool
+		MaxConcurrentStreams uint32
+		InitialWindowSize    uint32
+		MaxFrameSize         uint32
+		MaxHeaderListSize    uint32
+	}
+
+	// connSettings tracks settings of both endpoints on a connection.
+	// Our settings take effect when peer acknowledges them.
+	connSettings struct {
+		mu      sync.RWMutex
+		local   Settings
+		peer    Settings
+		pending []*pendingSettings
+	}
+
+	pendingSettings struct {
+		params   []*SettingsFrameParam
+		deadline time.Time
+	}
+)
+
+// DefaultSettings returns initial values of SETTINGS parameters.
+// Parameters without limit(MaxConcurrentStreams and MaxHeaderListSize) are math.MaxUint32.
+func DefaultSettings() Settings {
+	return Settings{
+		HeaderTableSize:      4096,
+		EnablePush:           true,
+		MaxConcurrentStreams: math.MaxUint32,
+		InitialWindowSize:    (1 << 16) - 1,
+		MaxFrameSize:         defaultMaxFrameSize,
+		MaxHeaderListSize:    math.MaxUint32,
+	}
+}
+
+func (s *Settings) apply(param *SettingsFrameParam) {
+	switch param.ID {
+	case HeaderTableSizeSetting:
+		s.HeaderTableSize = param.Value
+	case EnablePushSetting:
+		s.EnablePush = param.Value == 1
+	case MaxConcurrentStreamsSetting:
+		s.MaxConcurrentStreams = param.Value
+	case InitialWindowSizeSetting:
+		s.InitialWindowSize = param.Value
+	case MaxFrameSizeSetting:
+		s.MaxFrameSize = param.Value
+	case MaxHeaderListSizeSetting:
+		s.MaxHeaderListSize = param.Value
+	}
+}
+
+func newConnSettings() *connSettings {
+	return &connSettings{
+		local: DefaultSettings(),
+		peer:  DefaultSettings(),
+	}
+}
+
+func (cs *connSettings) localSettings() Settings {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.local
+}
+
+func (cs *connSettings) peerSettings() Settings {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.peer
+}
+
+// sent records our SETTINGS frame which must be acknowledged until the deadline.
+func (cs *connSettings) sent(settings *SettingsFrame, deadline time.Time) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.pending = append(cs.pending, &pendingSettings{params: settings.Params(), deadline: deadline})
+}
+
+// acknowledged applies the oldest SETTINGS frame not acknowledged yet.
+// It returns false if there is no such SETTINGS frame.
+func (cs *connSettings) acknowledged() bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if len(cs.pending) == 0 {
+		return false
+	}
+
+	for _, param := range cs.pending[0].params {
+		cs.local.apply(param)
+	}
+
+	cs.pending = cs.pending[1:]
+	return true
+}
+
+// ackDeadline returns the deadline of the oldest SETTINGS frame not acknowledged yet.
+func (cs *connSettings) ackDeadline() (time.Time, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	if len(cs.pending) == 0 {
+		return time.Time{}, false
+	}
+	return cs.pending[0].deadline, true
+}
+
+// received verifies and applies peer's SETTINGS frame.
+// Parameters are applied only if all of them are valid.
+func (cs *connSettings) received(settings *SettingsFrame) error {
+	params := settings.Params()
+	for _, param := range params {
+		if err := param.Verify(); err != nil {
+			return err
+		}
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for _, param := range params {
+		cs.peer.apply(param)
+	}
+	return nil
+}
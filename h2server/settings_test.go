@@ -0,0 +1,104 @@
+package h2server
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func buildSettingsFrame(t *testing.T, params ...*SettingsFrameParam) *SettingsFrame {
+	t.Helper()
+
+	f, err := NewSettingsFrameBuilder().Add(params...).Build()
+	if err != nil {
+		t.Fatalf("failed to build settings frame: %v", err)
+	}
+	return f.(*SettingsFrame)
+}
+
+func TestConnSettings_Received(t *testing.T) {
+	cs := newConnSettings()
+
+	err := cs.received(buildSettingsFrame(t,
+		&SettingsFrameParam{ID: HeaderTableSizeSetting, Value: 0},
+		&SettingsFrameParam{ID: EnablePushSetting, Value: 0},
+		&SettingsFrameParam{ID: MaxConcurrentStreamsSetting, Value: 100},
+		&SettingsFrameParam{ID: InitialWindowSizeSetting, Value: 1 << 20},
+		&SettingsFrameParam{ID: MaxFrameSizeSetting, Value: 1 << 15},
+		&SettingsFrameParam{ID: MaxHeaderListSizeSetting, Value: 1 << 12},
+	))
+	if err != nil {
+		t.Fatalf("received() got error = %v", err)
+	}
+
+	want := Settings{
+		HeaderTableSize:      0,
+		EnablePush:           false,
+		MaxConcurrentStreams: 100,
+		InitialWindowSize:    1 << 20,
+		MaxFrameSize:         1 << 15,
+		MaxHeaderListSize:    1 << 12,
+	}
+
+	if got := cs.peerSettings(); got != want {
+		t.Errorf("peerSettings() got = %+v, want = %+v", got, want)
+	}
+
+	if got := cs.localSettings(); got != DefaultSettings() {
+		t.Errorf("localSettings() got = %+v, want = %+v", got, DefaultSettings())
+	}
+}
+
+func TestConnSettings_Received_Invalid(t *testing.T) {
+	cs := newConnSettings()
+
+	invalid := &SettingsFrame{frame: &frame{
+		typ: SettingsFrameType,
+		payload: []byte{
+			0x00, 0x04, 0x00, 0x00, 0x10, 0x00, // INITIAL_WINDOW_SIZE = 4096
+			0x00, 0x02, 0x00, 0x00, 0x00, 0x02, // ENABLE_PUSH = 2
+		},
+	}}
+
+	if got := UnwrapErrorCode(cs.received(invalid)); got != ProtocolError {
+		t.Errorf("received() got = %s, want = %s", got, ProtocolError)
+	}
+
+	if got := cs.peerSettings(); got != DefaultSettings() {
+		t.Errorf("settings are applied partially: %+v", got)
+	}
+}
+
+func TestConnSettings_Acknowledged(t *testing.T) {
+	cs := newConnSettings()
+
+	if cs.acknowledged() {
+		t.Fatalf("acknowledged() got = true without pending settings")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	cs.sent(buildSettingsFrame(t, &SettingsFrameParam{ID: MaxConcurrentStreamsSetting, Value: 100}), deadline)
+	cs.sent(buildSettingsFrame(t, &SettingsFrameParam{ID: MaxConcurrentStreamsSetting, Value: 10}), deadline.Add(time.Second))
+
+	if got, ok := cs.ackDeadline(); !ok || !got.Equal(deadline) {
+		t.Errorf("ackDeadline() got = %v, want = %v", got, deadline)
+	}
+
+	if got := cs.localSettings().MaxConcurrentStreams; got != math.MaxUint32 {
+		t.Errorf("settings not acknowledged are applied: %d", got)
+	}
+
+	for _, want := range []uint32{100, 10} {
+		if !cs.acknowledged() {
+			t.Fatalf("acknowledged() got = false")
+		}
+
+		if got := cs.localSettings().MaxConcurrentStreams; got != want {
+			t.Errorf("localSettings().MaxConcurrentStreams got = %d, want = %d", got, want)
+		}
+	}
+
+	if _, ok := cs.ackDeadline(); ok {
+		t.Errorf("ackDeadline() got deadline after all settings are acknowledged")
+	}
+}
@@ -0,0 +1,58 @@
+package h2server
+
+type (
+	// gracefulShutdown sends GOAWAY twice to shut down a connection without dropping streams in flight.
+	// The first GOAWAY with max stream ID notifies peer of shutdown, and the final GOAWAY with actual last stream ID
+	// is sent after a PING round trip, so that streams peer initiated meanwhile are processed.
+	// It's used only by reader of the connection.
+	// See: https://tools.ietf.org/html/rfc7540#section-6.8
+	gracefulShutdown struct {
+		started  bool
+		finished bool
+	}
+)
+
+const (
+	maxStreamID = (1 << 31) - 1
+)
+
+var (
+	shutdownPingData = [8]byte{'s', 'h', 'u', 't', 'd', 'o', 'w', 'n'}
+)
+
+// start returns the first GOAWAY and PING to send in this order.
+// It returns nil if shutdown has been started already.
+func (gs *gracefulShutdown) start() ([]Frame, error) {
+	if gs.started {
+		return nil, nil
+	}
+
+	goAway, err := NewGoAwayFrameBuilder(maxStreamID, NoError).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	ping, err := NewPingFrameBuilder(shutdownPingData).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	gs.started = true
+	return []Frame{goAway, ping}, nil
+}
+
+// acknowledged returns the final GOAWAY if the ACK is for PING sent by start.
+// It returns nil if the ACK isn't for shutdown.
+func (gs *gracefulShutdown) acknowledged(ack *PingFrame, lastStreamID uint32) (Frame, error) {
+	if !gs.started || gs.finished || ack.Data() != shutdownPingData {
+		return nil, nil
+	}
+
+	goAway, err := NewGoAwayFrameBuilder(lastStreamID, NoError).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	gs.finished = true
+	return goAway, nil
+}
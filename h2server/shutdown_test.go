@@ -0,0 +1,50 @@
+package h2server
+
+import (
+	"testing"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	gs := &gracefulShutdown{}
+
+	frames, err := gs.start()
+	if err != nil || len(frames) != 2 {
+		t.Fatalf("start() got = %v, %v", frames, err)
+	}
+
+	if goAway, ok := frames[0].(*GoAwayFrame); !ok || goAway.LastStreamID() != maxStreamID || goAway.ErrorCode() != NoError {
+		t.Errorf("start() got first frame = %+v, want = go away with max stream ID", frames[0])
+	}
+
+	ping, ok := frames[1].(*PingFrame)
+	if !ok || ping.IsACK() {
+		t.Fatalf("start() got second frame = %+v, want = ping", frames[1])
+	}
+
+	if again, _ := gs.start(); again != nil {
+		t.Errorf("start() returns frames again")
+	}
+
+	other, err := NewPingFrameBuilder([8]byte{1}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherACK, _ := other.(*PingFrame).ACK()
+	if goAway, _ := gs.acknowledged(otherACK.(*PingFrame), 3); goAway != nil {
+		t.Errorf("acknowledged() accepts ACK for other PING")
+	}
+
+	ack, _ := ping.ACK()
+	goAway, err := gs.acknowledged(ack.(*PingFrame), 3)
+	if err != nil || goAway == nil || goAway.(*GoAwayFrame).LastStreamID() != 3 {
+		t.Fatalf("acknowledged() got = %+v, %v, want = go away with last stream ID(3)", goAway, err)
+	}
+
+	if !gs.finished {
+		t.Errorf("finished got = false after the final go away")
+	}
+
+	if again, _ := gs.acknowledged(ack.(*PingFrame), 5); again != nil {
+		t.Errorf("acknowledged() returns the final go away again")
+	}
+}
@@ -0,0 +1,199 @@
+package h2server
+
+import (
+	"context"
+
+	"github.com/murakmii/exp-h2server/h2server/hpack"
+)
+
+type (
+	// StreamState is state of stream.
+	// See: https://tools.ietf.org/html/rfc7540#section-5.1
+	StreamState uint8
+
+	stream struct {
+		id    uint32
+		state StreamState
+		send  sendFlow
+		recv  recvFlow
+
+		// header is header list received on the stream.
+		header hpack.HeaderList
+
+		// body receives DATA frames of the request, and cancel cancels context of the request.
+		// They're set when the handler is called.
+		body   *requestBody
+		cancel context.CancelFunc
+	}
+)
+
+const (
+	StreamIdle StreamState = iota
+	StreamReservedLocal
+	StreamReservedRemote
+	StreamOpen
+	StreamHalfClosedLocal
+	StreamHalfClosedRemote
+	StreamClosed
+)
+
+func (state StreamState) String() string {
+	switch state {
+	case StreamIdle:
+		return "idle"
+	case StreamReservedLocal:
+		return "reserved (local)"
+	case StreamReservedRemote:
+		return "reserved (remote)"
+	case StreamOpen:
+		return "open"
+	case StreamHalfClosedLocal:
+		return "half-closed (local)"
+	case StreamHalfClosedRemote:
+		return "half-closed (remote)"
+	case StreamClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+func newStream(id uint32, state StreamState) *stream {
+	return &stream{id: id, state: state}
+}
+
+func isClientStreamID(id uint32) bool {
+	return id%2 == 1
+}
+
+// recvHeaders changes state by receiving HEADERS frame.
+func (st *stream) recvHeaders(endStream bool) error {
+	switch st.state {
+	case StreamIdle:
+		st.state = StreamOpen
+
+	case StreamReservedRemote:
+		st.state = StreamHalfClosedLocal
+
+	case StreamOpen, StreamHalfClosedLocal:
+
+	case StreamHalfClosedRemote, StreamClosed:
+		return NewStreamError(st.id, StreamClosedError, "received headers frame on %s stream(%d)", st.state, st.id)
+
+	default:
+		return NewConnectionError(ProtocolError, "received headers frame on %s stream(%d)", st.state, st.id)
+	}
+
+	if endStream {
+		st.recvEndStream()
+	}
+	return nil
+}
+
+// recvData changes state by receiving DATA frame.
+func (st *stream) recvData(endStream bool) error {
+	switch st.state {
+	case StreamOpen, StreamHalfClosedLocal:
+
+	case StreamHalfClosedRemote, StreamClosed:
+		return NewStreamError(st.id, StreamClosedError, "received data frame on %s stream(%d)", st.state, st.id)
+
+	default:
+		return NewConnectionError(ProtocolError, "received data frame on %s stream(%d)", st.state, st.id)
+	}
+
+	if endStream {
+		st.recvEndStream()
+	}
+	return nil
+}
+
+// verifyRecv verifies that the stream can receive frames other than HEADERS, DATA, RST_STREAM and PRIORITY.
+func (st *stream) verifyRecv(typ FrameType) error {
+	switch st.state {
+	case StreamIdle:
+		return NewConnectionError(ProtocolError, "received frame(0x%X) on idle stream(%d)", typ, st.id)
+
+	case StreamReservedLocal:
+		if typ != WindowUpdateFrameType {
+			return NewConnectionError(ProtocolError, "received frame(0x%X) on reserved stream(%d)", typ, st.id)
+		}
+
+	case StreamHalfClosedRemote, StreamClosed:
+		if typ != WindowUpdateFrameType {
+			return NewStreamError(st.id, StreamClosedError, "received frame(0x%X) on %s stream(%d)", typ, st.state, st.id)
+		}
+	}
+
+	return nil
+}
+
+func (st *stream) recvEndStream() {
+	switch st.state {
+	case StreamOpen:
+		st.state = StreamHalfClosedRemote
+	case StreamHalfClosedLocal:
+		st.state = StreamClosed
+	}
+}
+
+// sendHeaders changes state by sending HEADERS frame.
+func (st *stream) sendHeaders(endStream bool) error {
+	switch st.state {
+	case StreamIdle:
+		st.state = StreamOpen
+
+	case StreamReservedLocal:
+		st.state = StreamHalfClosedRemote
+
+	case StreamOpen, StreamHalfClosedRemote:
+
+	default:
+		return NewStreamError(st.id, StreamClosedError, "can't send headers frame on %s stream(%d)", st.state, st.id)
+	}
+
+	if endStream {
+		st.sendEndStream()
+	}
+	return nil
+}
+
+// sendData changes state by sending DATA frame.
+func (st *stream) sendData(endStream bool) error {
+	if st.state != StreamOpen && st.state != StreamHalfClosedRemote {
+		return NewStreamError(st.id, StreamClosedError, "can't send data frame on %s stream(%d)", st.state, st.id)
+	}
+
+	if endStream {
+		st.sendEndStream()
+	}
+	return nil
+}
+
+// sendPushPromise reserves the stream by sending PUSH_PROMISE frame.
+func (st *stream) sendPushPromise() error {
+	if st.state != StreamIdle {
+		return NewStreamError(st.id, ProtocolError, "can't reserve %s stream(%d)", st.state, st.id)
+	}
+
+	st.state = StreamReservedLocal
+	return nil
+}
+
+func (st *stream) sendEndStream() {
+	switch st.state {
+	case StreamOpen:
+		st.state = StreamHalfClosedLocal
+	case StreamHalfClosedRemote:
+		st.state = StreamClosed
+	}
+}
+
+// close closes the stream by sending or receiving RST_STREAM frame.
+func (st *stream) close() {
+	st.state = StreamClosed
+}
+
+func (st *stream) isClosed() bool {
+	return st.state == StreamClosed
+}
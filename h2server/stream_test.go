@@ -0,0 +1,118 @@
+package h2server
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStream_Recv(t *testing.T) {
+	type want struct {
+		state     StreamState
+		streamErr ErrorCode
+		connErr   ErrorCode
+	}
+
+	tests := []struct {
+		state     StreamState
+		typ       FrameType
+		endStream bool
+		want      want
+	}{
+		{state: StreamIdle, typ: HeadersFrameType, want: want{state: StreamOpen}},
+		{state: StreamIdle, typ: HeadersFrameType, endStream: true, want: want{state: StreamHalfClosedRemote}},
+		{state: StreamReservedRemote, typ: HeadersFrameType, want: want{state: StreamHalfClosedLocal}},
+		{state: StreamReservedLocal, typ: HeadersFrameType, want: want{state: StreamReservedLocal, connErr: ProtocolError}},
+		{state: StreamOpen, typ: HeadersFrameType, endStream: true, want: want{state: StreamHalfClosedRemote}},
+		{state: StreamHalfClosedLocal, typ: HeadersFrameType, endStream: true, want: want{state: StreamClosed}},
+		{state: StreamHalfClosedRemote, typ: HeadersFrameType, want: want{state: StreamHalfClosedRemote, streamErr: StreamClosedError}},
+		{state: StreamClosed, typ: HeadersFrameType, want: want{state: StreamClosed, streamErr: StreamClosedError}},
+
+		{state: StreamIdle, typ: DataFrameType, want: want{state: StreamIdle, connErr: ProtocolError}},
+		{state: StreamReservedLocal, typ: DataFrameType, want: want{state: StreamReservedLocal, connErr: ProtocolError}},
+		{state: StreamOpen, typ: DataFrameType, want: want{state: StreamOpen}},
+		{state: StreamOpen, typ: DataFrameType, endStream: true, want: want{state: StreamHalfClosedRemote}},
+		{state: StreamHalfClosedLocal, typ: DataFrameType, endStream: true, want: want{state: StreamClosed}},
+		{state: StreamHalfClosedRemote, typ: DataFrameType, want: want{state: StreamHalfClosedRemote, streamErr: StreamClosedError}},
+		{state: StreamClosed, typ: DataFrameType, want: want{state: StreamClosed, streamErr: StreamClosedError}},
+
+		{state: StreamIdle, typ: WindowUpdateFrameType, want: want{state: StreamIdle, connErr: ProtocolError}},
+		{state: StreamReservedLocal, typ: WindowUpdateFrameType, want: want{state: StreamReservedLocal}},
+		{state: StreamHalfClosedRemote, typ: WindowUpdateFrameType, want: want{state: StreamHalfClosedRemote}},
+		{state: StreamClosed, typ: WindowUpdateFrameType, want: want{state: StreamClosed}},
+	}
+
+	for _, tt := range tests {
+		st := newStream(1, tt.state)
+
+		var err error
+		switch tt.typ {
+		case HeadersFrameType:
+			err = st.recvHeaders(tt.endStream)
+		case DataFrameType:
+			err = st.recvData(tt.endStream)
+		default:
+			err = st.verifyRecv(tt.typ)
+		}
+
+		var streamErr *StreamError
+		var connErr *ConnectionError
+		switch {
+		case tt.want.streamErr != NoError:
+			if !errors.As(err, &streamErr) || streamErr.Code() != tt.want.streamErr {
+				t.Errorf("recv 0x%X on %s stream got error = %v, want stream error %s", tt.typ, tt.state, err, tt.want.streamErr)
+			}
+		case tt.want.connErr != NoError:
+			if !errors.As(err, &connErr) || connErr.Code() != tt.want.connErr {
+				t.Errorf("recv 0x%X on %s stream got error = %v, want connection error %s", tt.typ, tt.state, err, tt.want.connErr)
+			}
+		case err != nil:
+			t.Errorf("recv 0x%X on %s stream got error = %v", tt.typ, tt.state, err)
+		}
+
+		if st.state != tt.want.state {
+			t.Errorf("recv 0x%X on %s stream got state = %s, want = %s", tt.typ, tt.state, st.state, tt.want.state)
+		}
+	}
+}
+
+func TestStream_Send(t *testing.T) {
+	tests := []struct {
+		state     StreamState
+		typ       FrameType
+		endStream bool
+		want      StreamState
+		wantErr   bool
+	}{
+		{state: StreamIdle, typ: PushPromiseFrameType, want: StreamReservedLocal},
+		{state: StreamOpen, typ: PushPromiseFrameType, want: StreamOpen, wantErr: true},
+		{state: StreamReservedLocal, typ: HeadersFrameType, want: StreamHalfClosedRemote},
+		{state: StreamHalfClosedRemote, typ: HeadersFrameType, want: StreamHalfClosedRemote},
+		{state: StreamHalfClosedRemote, typ: HeadersFrameType, endStream: true, want: StreamClosed},
+		{state: StreamOpen, typ: DataFrameType, endStream: true, want: StreamHalfClosedLocal},
+		{state: StreamHalfClosedRemote, typ: DataFrameType, endStream: true, want: StreamClosed},
+		{state: StreamHalfClosedLocal, typ: DataFrameType, want: StreamHalfClosedLocal, wantErr: true},
+		{state: StreamClosed, typ: HeadersFrameType, want: StreamClosed, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		st := newStream(2, tt.state)
+
+		var err error
+		switch tt.typ {
+		case HeadersFrameType:
+			err = st.sendHeaders(tt.endStream)
+		case DataFrameType:
+			err = st.sendData(tt.endStream)
+		case PushPromiseFrameType:
+			err = st.sendPushPromise()
+		}
+
+		if (err != nil) != tt.wantErr {
+			t.Errorf("send 0x%X on %s stream got error = %v, want error = %v", tt.typ, tt.state, err, tt.wantErr)
+		}
+
+		if st.state != tt.want {
+			t.Errorf("send 0x%X on %s stream got state = %s, want = %s", tt.typ, tt.state, st.state, tt.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package h2server
+
+import (
+	"errors"
+	"fmt"
+)
+
+type (
+	// TerminationCause is category of reason why connection is terminated.
+	TerminationCause uint8
+
+	// Termination is reason why connection is terminated, passed to Multiplexer.Terminated.
+	Termination struct {
+		Cause TerminationCause
+
+		// Code is error code of GOAWAY received from peer or sent to peer.
+		// It's NoError if connection is terminated without GOAWAY by I/O error.
+		Code ErrorCode
+
+		// DebugData is additional debug data of GOAWAY received from peer.
+		DebugData []byte
+
+		// Err is error terminated the connection, or nil if it's terminated gracefully.
+		Err error
+	}
+
+	// ConnState is state of connection notified to ServerConfig.ConnState.
+	ConnState uint8
+
+	// peerGoAwayError is returned by reader when connection is terminated by GOAWAY from peer.
+	peerGoAwayError struct {
+		code      ErrorCode
+		debugData []byte
+	}
+)
+
+const (
+	// TerminatedByShutdown means connection is closed by Shutdown, Close, max age or idle timeout.
+	TerminatedByShutdown TerminationCause = iota
+
+	// TerminatedByPeer means peer sent GOAWAY.
+	TerminatedByPeer
+
+	// TerminatedByProtocolError means the server detected connection error and sent GOAWAY.
+	TerminatedByProtocolError
+
+	// TerminatedByIOError means connection can't be read or written.
+	TerminatedByIOError
+)
+
+const (
+	// ConnNew is state of connection just accepted.
+	ConnNew ConnState = iota
+
+	// ConnHandshaking is state of connection in TLS handshake and exchange of connection prefaces.
+	ConnHandshaking
+
+	// ConnActive is state of connection with active streams.
+	ConnActive
+
+	// ConnIdle is state of connection without active streams.
+	ConnIdle
+
+	// ConnDraining is state of connection going away. New streams aren't accepted.
+	ConnDraining
+
+	// ConnClosed is state of closed connection.
+	ConnClosed
+)
+
+var (
+	_ error = (*peerGoAwayError)(nil)
+)
+
+func (cause TerminationCause) String() string {
+	switch cause {
+	case TerminatedByShutdown:
+		return "shutdown"
+	case TerminatedByPeer:
+		return "peer go away"
+	case TerminatedByProtocolError:
+		return "protocol error"
+	case TerminatedByIOError:
+		return "i/o error"
+	default:
+		return fmt.Sprintf("unknown termination cause(%d)", cause)
+	}
+}
+
+func (t *Termination) String() string {
+	if t.Err == nil {
+		return fmt.Sprintf("%s(%s)", t.Cause, t.Code)
+	}
+	return fmt.Sprintf("%s(%s): %s", t.Cause, t.Code, t.Err.Error())
+}
+
+// newTermination classifies errors returned by reader and writer of the connection.
+// Errors of reader take precedence because writer stops after reader in most cases.
+func newTermination(rErr, wErr error, shuttingDown bool) *Termination {
+	var peerErr *peerGoAwayError
+	if errors.As(rErr, &peerErr) {
+		return &Termination{Cause: TerminatedByPeer, Code: peerErr.code, DebugData: peerErr.debugData, Err: rErr}
+	}
+
+	var connErr *ConnectionError
+	if errors.As(rErr, &connErr) {
+		if connErr.Code() == NoError {
+			return &Termination{Cause: TerminatedByShutdown, Code: NoError, Err: rErr}
+		}
+		return &Termination{Cause: TerminatedByProtocolError, Code: connErr.Code(), Err: rErr}
+	}
+
+	err := rErr
+	if err == nil {
+		err = wErr
+	}
+
+	// Connections are closed by Close while shutting down.
+	if err == nil || shuttingDown {
+		return &Termination{Cause: TerminatedByShutdown, Code: NoError, Err: err}
+	}
+	return &Termination{Cause: TerminatedByIOError, Code: NoError, Err: err}
+}
+
+func (state ConnState) String() string {
+	switch state {
+	case ConnNew:
+		return "new"
+	case ConnHandshaking:
+		return "handshaking"
+	case ConnActive:
+		return "active"
+	case ConnIdle:
+		return "idle"
+	case ConnDraining:
+		return "draining"
+	case ConnClosed:
+		return "closed"
+	default:
+		return fmt.Sprintf("unknown conn state(%d)", state)
+	}
+}
+
+func (err *peerGoAwayError) Error() string {
+	return fmt.Sprintf("received go away(%s): %s", err.code, string(err.debugData))
+}
@@ -0,0 +1,43 @@
+package h2server
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestNewTermination(t *testing.T) {
+	tests := []struct {
+		rErr         error
+		wErr         error
+		shuttingDown bool
+		cause        TerminationCause
+		code         ErrorCode
+	}{
+		{rErr: nil, wErr: nil, cause: TerminatedByShutdown, code: NoError},
+		{rErr: &peerGoAwayError{code: EnhanceYourCalmError}, cause: TerminatedByPeer, code: EnhanceYourCalmError},
+		{rErr: NewConnectionError(ProtocolError, "sample"), cause: TerminatedByProtocolError, code: ProtocolError},
+		{rErr: NewConnectionError(NoError, "idle"), cause: TerminatedByShutdown, code: NoError},
+		{rErr: io.EOF, cause: TerminatedByIOError, code: NoError},
+		{rErr: nil, wErr: io.ErrClosedPipe, cause: TerminatedByIOError, code: NoError},
+		{rErr: io.ErrClosedPipe, shuttingDown: true, cause: TerminatedByShutdown, code: NoError},
+	}
+
+	for _, tt := range tests {
+		got := newTermination(tt.rErr, tt.wErr, tt.shuttingDown)
+		if got.Cause != tt.cause || got.Code != tt.code {
+			t.Errorf("newTermination(%v, %v, %v) got = %s, %s, want = %s, %s", tt.rErr, tt.wErr, tt.shuttingDown, got.Cause, got.Code, tt.cause, tt.code)
+		}
+
+		if err := tt.rErr; err != nil && !errors.Is(got.Err, err) {
+			t.Errorf("newTermination(%v, %v, %v) got error = %v", tt.rErr, tt.wErr, tt.shuttingDown, got.Err)
+		}
+	}
+}
+
+func TestNewTermination_PeerDebugData(t *testing.T) {
+	got := newTermination(&peerGoAwayError{code: NoError, debugData: []byte("bye")}, nil, false)
+	if got.Cause != TerminatedByPeer || string(got.DebugData) != "bye" {
+		t.Errorf("newTermination() got = %+v", got)
+	}
+}
@@ -0,0 +1,100 @@
+package h2server
+
+import (
+	"strings"
+
+	"github.com/murakmii/exp-h2server/h2server/hpack"
+)
+
+// validateRequestHeader returns stream error of PROTOCOL_ERROR if header list of request is malformed.
+// See: https://tools.ietf.org/html/rfc7540#section-8.1.2
+func validateRequestHeader(streamID uint32, headerList hpack.HeaderList) error {
+	pseudo := make(map[string]string)
+	regular := false
+
+	for _, hf := range headerList {
+		name := hf.Name()
+
+		if strings.HasPrefix(name, ":") {
+			// See: https://tools.ietf.org/html/rfc7540#section-8.1.2.1
+			switch {
+			case regular:
+				return NewStreamError(streamID, ProtocolError, "pseudo-header(%s) of stream(%d) follows regular header", name, streamID)
+
+			case name != ":method" && name != ":scheme" && name != ":authority" && name != ":path":
+				return NewStreamError(streamID, ProtocolError, "pseudo-header(%s) of stream(%d) isn't defined for request", name, streamID)
+			}
+
+			if _, ok := pseudo[name]; ok {
+				return NewStreamError(streamID, ProtocolError, "pseudo-header(%s) of stream(%d) is duplicated", name, streamID)
+			}
+
+			pseudo[name] = hf.Value()
+			continue
+		}
+
+		regular = true
+		if err := validateRegularHeader(streamID, hf); err != nil {
+			return err
+		}
+	}
+
+	// CONNECT request has only :method and :authority.
+	// See: https://tools.ietf.org/html/rfc7540#section-8.3
+	if pseudo[":method"] == "CONNECT" {
+		_, hasScheme := pseudo[":scheme"]
+		_, hasPath := pseudo[":path"]
+
+		if hasScheme || hasPath || pseudo[":authority"] == "" {
+			return NewStreamError(streamID, ProtocolError, "CONNECT request of stream(%d) has invalid pseudo-headers", streamID)
+		}
+		return nil
+	}
+
+	// See: https://tools.ietf.org/html/rfc7540#section-8.1.2.3
+	for _, name := range []string{":method", ":scheme", ":path"} {
+		if pseudo[name] == "" {
+			return NewStreamError(streamID, ProtocolError, "request of stream(%d) doesn't have pseudo-header(%s)", streamID, name)
+		}
+	}
+
+	return nil
+}
+
+// validateTrailer returns stream error of PROTOCOL_ERROR if trailers contain pseudo-header or connection-specific fields.
+// See: https://tools.ietf.org/html/rfc7540#section-8.1.2.1
+func validateTrailer(streamID uint32, headerList hpack.HeaderList) error {
+	for _, hf := range headerList {
+		if strings.HasPrefix(hf.Name(), ":") {
+			return NewStreamError(streamID, ProtocolError, "trailers of stream(%d) contain pseudo-header(%s)", streamID, hf.Name())
+		}
+
+		if err := validateRegularHeader(streamID, hf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateRegularHeader verifies that the header field is lower case and isn't connection-specific.
+// See: https://tools.ietf.org/html/rfc7540#section-8.1.2.2
+func validateRegularHeader(streamID uint32, hf *hpack.HeaderField) error {
+	name := hf.Name()
+
+	if strings.ToLower(name) != name {
+		return NewStreamError(streamID, ProtocolError, "header(%s) of stream(%d) isn't lower case", name, streamID)
+	}
+
+	switch name {
+	case "connection", "keep-alive", "proxy-connection", "transfer-encoding", "upgrade":
+		return NewStreamError(streamID, ProtocolError, "connection-specific header(%s) is sent on stream(%d)", name, streamID)
+
+	case "te":
+		if hf.Value() != "trailers" {
+			return NewStreamError(streamID, ProtocolError, "te header of stream(%d) has value other than trailers", streamID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package h2server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/murakmii/exp-h2server/h2server/hpack"
+)
+
+func TestValidateTrailer(t *testing.T) {
+	tests := []struct {
+		name    string
+		trailer hpack.HeaderList
+		valid   bool
+	}{
+		{
+			name:    "valid trailers",
+			trailer: hpack.HeaderList{hpack.NewHeaderField("grpc-status", "0")},
+			valid:   true,
+		},
+		{
+			name:    "pseudo-header",
+			trailer: hpack.HeaderList{hpack.NewHeaderField("grpc-status", "0"), hpack.NewHeaderField(":status", "200")},
+		},
+		{
+			name:    "connection-specific header",
+			trailer: hpack.HeaderList{hpack.NewHeaderField("connection", "close")},
+		},
+	}
+
+	for _, tt := range tests {
+		err := validateTrailer(1, tt.trailer)
+		if tt.valid != (err == nil) {
+			t.Errorf("%s: validateTrailer() got error = %v", tt.name, err)
+		}
+	}
+}
+
+func TestValidateRequestHeader(t *testing.T) {
+	hl := func(fields ...string) hpack.HeaderList {
+		var headerList hpack.HeaderList
+		for i := 0; i < len(fields); i += 2 {
+			headerList = append(headerList, hpack.NewHeaderField(fields[i], fields[i+1]))
+		}
+		return headerList
+	}
+
+	tests := []struct {
+		name   string
+		header hpack.HeaderList
+		valid  bool
+	}{
+		{
+			name:   "valid request",
+			header: hl(":method", "GET", ":scheme", "https", ":authority", "example.com", ":path", "/", "accept", "*/*"),
+			valid:  true,
+		},
+		{
+			name:   "without :authority",
+			header: hl(":method", "GET", ":scheme", "https", ":path", "/"),
+			valid:  true,
+		},
+		{
+			name:   "without :method",
+			header: hl(":scheme", "https", ":path", "/"),
+		},
+		{
+			name:   "without :scheme",
+			header: hl(":method", "GET", ":path", "/"),
+		},
+		{
+			name:   "empty :path",
+			header: hl(":method", "GET", ":scheme", "https", ":path", ""),
+		},
+		{
+			name:   "valid CONNECT",
+			header: hl(":method", "CONNECT", ":authority", "example.com:443"),
+			valid:  true,
+		},
+		{
+			name:   "CONNECT with :path",
+			header: hl(":method", "CONNECT", ":authority", "example.com:443", ":path", "/"),
+		},
+		{
+			name:   "CONNECT without :authority",
+			header: hl(":method", "CONNECT"),
+		},
+		{
+			name:   "pseudo-header after regular header",
+			header: hl(":method", "GET", ":scheme", "https", "accept", "*/*", ":path", "/"),
+		},
+		{
+			name:   "duplicated pseudo-header",
+			header: hl(":method", "GET", ":method", "POST", ":scheme", "https", ":path", "/"),
+		},
+		{
+			name:   ":status in request",
+			header: hl(":status", "200", ":method", "GET", ":scheme", "https", ":path", "/"),
+		},
+		{
+			name:   "upper case name",
+			header: hl(":method", "GET", ":scheme", "https", ":path", "/", "Accept", "*/*"),
+		},
+		{
+			name:   "connection-specific header",
+			header: hl(":method", "GET", ":scheme", "https", ":path", "/", "connection", "keep-alive"),
+		},
+		{
+			name:   "transfer-encoding",
+			header: hl(":method", "POST", ":scheme", "https", ":path", "/", "transfer-encoding", "chunked"),
+		},
+		{
+			name:   "te: trailers",
+			header: hl(":method", "GET", ":scheme", "https", ":path", "/", "te", "trailers"),
+			valid:  true,
+		},
+		{
+			name:   "te: gzip",
+			header: hl(":method", "GET", ":scheme", "https", ":path", "/", "te", "gzip"),
+		},
+	}
+
+	for _, tt := range tests {
+		err := validateRequestHeader(1, tt.header)
+
+		if tt.valid {
+			if err != nil {
+				t.Errorf("%s: validateRequestHeader() got error = %v", tt.name, err)
+			}
+			continue
+		}
+
+		var streamErr *StreamError
+		if !errors.As(err, &streamErr) || streamErr.StreamID() != 1 || streamErr.Code() != ProtocolError {
+			t.Errorf("%s: validateRequestHeader() got error = %v, want stream error %s", tt.name, err, ProtocolError)
+		}
+	}
+}